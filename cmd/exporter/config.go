@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix prefixes the environment variables that override settings.
+const envPrefix = "EXPORTER_"
+
+// configFile is the --config flag.
+var configFile string
+
+// setting is a flag that can also be set from the config file or from an
+// environment variable named after the flag (EXPORTER_SOURCE_DSN for
+// --source-dsn).
+type setting struct {
+	flag string
+	// key is the dotted path of the setting in the config file.
+	key string
+	// commands limits the setting to these commands; by default it applies
+	// to every command that has the flag.
+	commands []string
+	// group names settings that are alternatives to each other: the
+	// highest-precedence source that sets one of them wins for all of them.
+	group string
+}
+
+var settings = []setting{
+	{flag: "source-system", key: "source.system"},
+	{flag: "source-type", key: "source.type"},
+	{flag: "source-dsn", key: "source.dsn", group: "dsn"},
+	{flag: "source-dsn-file", key: "source.dsn_file", group: "dsn"},
+	{flag: "source-path", key: "source.path"},
+	{flag: "output", key: "output", commands: []string{"export"}},
+	{flag: "include-tokens", key: "include.tokens"},
+	{flag: "include-abilities", key: "include.abilities"},
+	{flag: "include-redemptions", key: "include.redemptions"},
+	{flag: "include-administrators", key: "include.administrators"},
+	{flag: "stream", key: "stream"},
+	{flag: "batch-size", key: "batch_size"},
+	{flag: "snapshot", key: "snapshot"},
+	{flag: "backup", key: "backup"},
+	{flag: "type-map", key: "mappings.type_map"},
+	{flag: "quota-per-unit", key: "masters.quota_per_unit"},
+	{flag: "max-child-keys", key: "masters.max_child_keys"},
+	{flag: "global-qps", key: "masters.global_qps"},
+	{flag: "redact", key: "redaction.mode"},
+	{flag: "redact-salt", key: "redaction.salt"},
+	{flag: "fail-on", key: "warnings.fail_on"},
+	{flag: "suppress", key: "warnings.suppress"},
+	{flag: "encrypt", key: "encryption.enabled"},
+	{flag: "passphrase-file", key: "encryption.passphrase_file"},
+	{flag: "recipient", key: "encryption.recipients"},
+	{flag: "decrypt-key", key: "decrypt_key"},
+	{flag: "target-url", key: "push.target_url"},
+	{flag: "admin-token", key: "push.admin_token", group: "admin-token"},
+	{flag: "admin-token-file", key: "push.admin_token_file", group: "admin-token"},
+	{flag: "progress-log", key: "push.progress_log"},
+	{flag: "timeout", key: "push.timeout", commands: []string{"push"}},
+	{flag: "verbose", key: "verbose"},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "YAML config file with default settings (env: EXPORTER_CONFIG)")
+	rootCmd.PersistentPreRunE = applySettings
+}
+
+// envName returns the environment variable of a setting.
+func (s setting) envName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.flag, "-", "_"))
+}
+
+// appliesTo reports whether the setting applies to cmd.
+func (s setting) appliesTo(cmd *cobra.Command) bool {
+	if cmd.Flags().Lookup(s.flag) == nil {
+		return false
+	}
+	if s.commands == nil {
+		return true
+	}
+	for _, name := range s.commands {
+		if cmd.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applySettings sets the flags of cmd that were not given on the command
+// line from the environment, then from the config file (--config or
+// EXPORTER_CONFIG). Flags take precedence over the environment, which takes
+// precedence over the file.
+func applySettings(cmd *cobra.Command, args []string) error {
+	if !cmd.Flags().Changed("config") {
+		configFile = os.Getenv(envPrefix + "CONFIG")
+	}
+
+	var fileValues map[string]interface{}
+	if configFile != "" {
+		var err error
+		if fileValues, err = loadConfig(configFile); err != nil {
+			return err
+		}
+	}
+
+	// Groups already set by a higher-precedence source
+	cmdlineGroups := map[string]bool{}
+	envGroups := map[string]bool{}
+	for _, s := range settings {
+		if s.group == "" || !s.appliesTo(cmd) {
+			continue
+		}
+		if cmd.Flags().Changed(s.flag) {
+			cmdlineGroups[s.group] = true
+		}
+		if os.Getenv(s.envName()) != "" {
+			envGroups[s.group] = true
+		}
+	}
+
+	for _, s := range settings {
+		if !s.appliesTo(cmd) || cmd.Flags().Changed(s.flag) || cmdlineGroups[s.group] {
+			continue
+		}
+
+		if value := os.Getenv(s.envName()); value != "" {
+			if err := setFlag(cmd.Flags(), s.flag, value); err != nil {
+				return fmt.Errorf("invalid %s: %w", s.envName(), err)
+			}
+			continue
+		}
+		if envGroups[s.group] {
+			continue
+		}
+
+		value, ok := fileValues[s.key]
+		if !ok {
+			continue
+		}
+		if err := setFlagFromConfig(cmd.Flags(), s.flag, value); err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", s.key, configFile, err)
+		}
+	}
+
+	return nil
+}
+
+// loadConfig reads a YAML config file and returns its settings by dotted key.
+// Unknown keys are rejected so that typos do not go unnoticed.
+func loadConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+	}
+
+	values := map[string]interface{}{}
+	var unknown []string
+	var walk func(prefix string, node map[string]interface{})
+	walk = func(prefix string, node map[string]interface{}) {
+		for name, value := range node {
+			key := prefix + name
+			if known[key] {
+				values[key] = value
+				continue
+			}
+			if child, ok := value.(map[string]interface{}); ok {
+				walk(key+".", child)
+				continue
+			}
+			unknown = append(unknown, key)
+		}
+	}
+	walk("", root)
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown settings in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// setFlag sets a flag from an environment variable. The values of
+// repeatable flags are comma-separated.
+func setFlag(flags *pflag.FlagSet, name, value string) error {
+	if flags.Lookup(name).Value.Type() != "stringArray" {
+		return flags.Set(name, value)
+	}
+	for _, v := range strings.Split(value, ",") {
+		if err := flags.Set(name, strings.TrimSpace(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFlagFromConfig sets a flag from a config file value. Lists set
+// repeatable flags once per element.
+func setFlagFromConfig(flags *pflag.FlagSet, name string, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, elem := range v {
+			if _, ok := elem.(map[string]interface{}); ok {
+				return fmt.Errorf("expected a list of values")
+			}
+			if err := flags.Set(name, fmt.Sprint(elem)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		return fmt.Errorf("expected a value, got a mapping")
+	}
+	return flags.Set(name, fmt.Sprint(value))
+}
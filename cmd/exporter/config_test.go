@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// newSettingsCommand returns a command with a subset of the export and
+// push flags, parsed from args.
+func newSettingsCommand(t *testing.T, name string, args ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{Use: name}
+	f := cmd.Flags()
+	f.String("source-type", "mysql", "")
+	f.String("source-dsn", "", "")
+	f.String("source-dsn-file", "", "")
+	f.String("output", "export.json", "")
+	f.Int("batch-size", 1000, "")
+	f.Bool("include-abilities", false, "")
+	f.StringSlice("suppress", nil, "")
+	f.StringArray("recipient", nil, "")
+	f.String("admin-token", "", "")
+	f.String("admin-token-file", "", "")
+	if err := f.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+// useConfig writes a config file and selects it with EXPORTER_CONFIG.
+func useConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "exporter.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXPORTER_CONFIG", path)
+	return path
+}
+
+// flagValue returns the value of a flag as shown in help output.
+func flagValue(t *testing.T, cmd *cobra.Command, name string) string {
+	t.Helper()
+	return cmd.Flags().Lookup(name).Value.String()
+}
+
+func TestApplySettingsPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string   // source.type in the config file
+		env  string   // EXPORTER_SOURCE_TYPE
+		args []string // Command line
+		want string
+	}{
+		{name: "default", want: "mysql"},
+		{name: "file", file: "sqlite", want: "sqlite"},
+		{name: "env over file", file: "sqlite", env: "postgres", want: "postgres"},
+		{name: "flag over env and file", file: "sqlite", env: "postgres", args: []string{"--source-type", "mysql"}, want: "mysql"},
+		{name: "flag over file", file: "sqlite", args: []string{"--source-type", "postgres"}, want: "postgres"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile = ""
+			content := ""
+			if tt.file != "" {
+				content = "source:\n  type: " + tt.file + "\n"
+			}
+			useConfig(t, content)
+			t.Setenv("EXPORTER_SOURCE_TYPE", tt.env)
+
+			cmd := newSettingsCommand(t, "export", tt.args...)
+			if err := applySettings(cmd, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := flagValue(t, cmd, "source-type"); got != tt.want {
+				t.Errorf("source-type %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySettingsGroups(t *testing.T) {
+	tests := []struct {
+		name           string
+		file           string
+		env            map[string]string
+		args           []string
+		dsn, dsnFile   string
+		token, tokenFn string
+	}{
+		{
+			name:    "file sets both alternatives",
+			file:    "source:\n  dsn: file-dsn\n  dsn_file: /file/dsn\n",
+			dsn:     "file-dsn",
+			dsnFile: "/file/dsn",
+		},
+		{
+			name:    "env dsn hides file dsn_file",
+			file:    "source:\n  dsn_file: /file/dsn\n",
+			env:     map[string]string{"EXPORTER_SOURCE_DSN": "env-dsn"},
+			dsn:     "env-dsn",
+			dsnFile: "",
+		},
+		{
+			name:    "flag dsn_file hides env dsn",
+			env:     map[string]string{"EXPORTER_SOURCE_DSN": "env-dsn"},
+			args:    []string{"--source-dsn-file", "/flag/dsn"},
+			dsn:     "",
+			dsnFile: "/flag/dsn",
+		},
+		{
+			name:    "file admin_token_file",
+			file:    "push:\n  admin_token_file: /run/secrets/token\n",
+			tokenFn: "/run/secrets/token",
+		},
+		{
+			name:  "env admin token hides file admin_token_file",
+			file:  "push:\n  admin_token_file: /run/secrets/token\n",
+			env:   map[string]string{"EXPORTER_ADMIN_TOKEN": "env-token"},
+			token: "env-token",
+		},
+		{
+			name:    "env admin token file hides file admin_token",
+			file:    "push:\n  admin_token: file-token\n",
+			env:     map[string]string{"EXPORTER_ADMIN_TOKEN_FILE": "/env/token"},
+			tokenFn: "/env/token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile = ""
+			useConfig(t, tt.file)
+			for _, name := range []string{"EXPORTER_SOURCE_DSN", "EXPORTER_SOURCE_DSN_FILE", "EXPORTER_ADMIN_TOKEN", "EXPORTER_ADMIN_TOKEN_FILE"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			cmd := newSettingsCommand(t, "export", tt.args...)
+			if err := applySettings(cmd, nil); err != nil {
+				t.Fatal(err)
+			}
+			got := [4]string{
+				flagValue(t, cmd, "source-dsn"), flagValue(t, cmd, "source-dsn-file"),
+				flagValue(t, cmd, "admin-token"), flagValue(t, cmd, "admin-token-file"),
+			}
+			if want := [4]string{tt.dsn, tt.dsnFile, tt.token, tt.tokenFn}; got != want {
+				t.Errorf("dsn, dsn file, token, token file = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestApplySettingsLists(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		env        map[string]string
+		suppress   string
+		recipients string
+	}{
+		{
+			name:       "file lists",
+			file:       "warnings:\n  suppress: [MULTIPLE_GROUPS, UNSUPPORTED_PRIORITY]\nencryption:\n  recipients:\n    - age1a\n    - age1b\n",
+			suppress:   "[MULTIPLE_GROUPS,UNSUPPORTED_PRIORITY]",
+			recipients: "[age1a,age1b]",
+		},
+		{
+			name:       "env lists are comma-separated",
+			env:        map[string]string{"EXPORTER_SUPPRESS": "MULTIPLE_GROUPS,UNSUPPORTED_PRIORITY", "EXPORTER_RECIPIENT": "age1a, age1b"},
+			suppress:   "[MULTIPLE_GROUPS,UNSUPPORTED_PRIORITY]",
+			recipients: "[age1a,age1b]",
+		},
+		{
+			name:       "env list replaces file list",
+			file:       "encryption:\n  recipients: [age1file]\n",
+			env:        map[string]string{"EXPORTER_RECIPIENT": "age1env"},
+			suppress:   "[]",
+			recipients: "[age1env]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile = ""
+			useConfig(t, tt.file)
+			t.Setenv("EXPORTER_SUPPRESS", tt.env["EXPORTER_SUPPRESS"])
+			t.Setenv("EXPORTER_RECIPIENT", tt.env["EXPORTER_RECIPIENT"])
+
+			cmd := newSettingsCommand(t, "export")
+			if err := applySettings(cmd, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := flagValue(t, cmd, "suppress"); got != tt.suppress {
+				t.Errorf("suppress %s, want %s", got, tt.suppress)
+			}
+			if got := flagValue(t, cmd, "recipient"); got != tt.recipients {
+				t.Errorf("recipient %s, want %s", got, tt.recipients)
+			}
+		})
+	}
+}
+
+func TestApplySettingsCommands(t *testing.T) {
+	configFile = ""
+	useConfig(t, "output: out.json\n")
+
+	// output only applies to export, even where another command has the flag
+	for _, tt := range []struct{ command, want string }{{"export", "out.json"}, {"diff", "export.json"}} {
+		cmd := newSettingsCommand(t, tt.command)
+		if err := applySettings(cmd, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := flagValue(t, cmd, "output"); got != tt.want {
+			t.Errorf("%s: output %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestApplySettingsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  string // EXPORTER_BATCH_SIZE
+		err  string
+	}{
+		{name: "unknown keys", file: "outptu: x.json\nsource:\n  sytem: newapi\n  type: mysql\n", err: "unknown settings in config file %s: outptu, source.sytem"},
+		{name: "unknown section", file: "pushh:\n  target_url: x\n", err: "unknown settings in config file %s: pushh.target_url"},
+		{name: "invalid YAML", file: "source: [", err: "failed to parse config file %s"},
+		{name: "mapping for a value", file: "batch_size:\n  n: 1\n", err: "invalid batch_size in %s: expected a value, got a mapping"},
+		{name: "invalid file value", file: "batch_size: many\n", err: "invalid batch_size in %s"},
+		{name: "invalid env value", env: "many", err: "invalid EXPORTER_BATCH_SIZE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile = ""
+			path := useConfig(t, tt.file)
+			t.Setenv("EXPORTER_BATCH_SIZE", tt.env)
+
+			err := applySettings(newSettingsCommand(t, "export"), nil)
+			want := tt.err
+			if strings.Contains(want, "%s") {
+				want = fmt.Sprintf(want, path)
+			}
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := useConfig(t, "source:\n  system: oneapi\n  dsn_file: /run/dsn\ninclude:\n  tokens: false\nbatch_size: 500\nwarnings:\n  suppress: [A, B]\n")
+
+	values, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprint(values)
+	want := "map[batch_size:500 include.tokens:false source.dsn_file:/run/dsn source.system:oneapi warnings.suppress:[A B]]"
+	if got != want {
+		t.Errorf("loadConfig = %s, want %s", got, want)
+	}
+
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing config file loaded")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"", time.Time{}},
+		{"2025-03-01T08:30:00Z", time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{"2025-03-01T08:30:00+08:00", time.Date(2025, 3, 1, 0, 30, 0, 0, time.UTC)},
+		{"1740787200", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0", time.Unix(0, 0)},
+		{"2025-03-01 08:30:00", time.Date(2025, 3, 1, 8, 30, 0, 0, time.Local)},
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		since, baseline, err := parseSince(tt.value)
+		if err != nil {
+			t.Errorf("parseSince(%q): %v", tt.value, err)
+			continue
+		}
+		if !since.Equal(tt.want) || baseline != nil {
+			t.Errorf("parseSince(%q) = %s, %v, want %s without baseline", tt.value, since, baseline, tt.want)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "2025-03-01T08:30:00", "03/01/2025", "1.5", t.TempDir()} {
+		if _, _, err := parseSince(value); err == nil || !strings.Contains(err.Error(), "invalid --since") {
+			t.Errorf("parseSince(%q): got error %v, want invalid --since", value, err)
+		}
+	}
+}
+
+func TestParseSinceExportFile(t *testing.T) {
+	exportedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	snapshotAt := exportedAt.Add(-time.Minute)
+
+	previous := schema.NewExportResult()
+	previous.Source.ExportedAt = exportedAt
+	previous.Data.Masters = []schema.Master{{SourceUserID: 4, Name: "dave"}}
+
+	write := func(r *schema.ExportResult) string {
+		t.Helper()
+		data, err := r.ToJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "previous.json")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	since, baseline, err := parseSince(write(previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !since.Equal(exportedAt) || baseline == nil || baseline.IDs[schema.TombstoneMaster][4] != "dave" {
+		t.Errorf("got %s, %+v, want the export time and a baseline with dave", since, baseline)
+	}
+
+	// The snapshot time is preferred, since rows changed during the export
+	// may have been missed
+	previous.Source.SnapshotAt = &snapshotAt
+	if since, _, err = parseSince(write(previous)); err != nil || !since.Equal(snapshotAt) {
+		t.Errorf("got %s, %v, want the snapshot time %s", since, err, snapshotAt)
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(path, []byte(`{"version":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := parseSince(path); err == nil || !strings.Contains(err.Error(), "invalid export file") {
+		t.Errorf("got error %v, want invalid export file", err)
+	}
+}
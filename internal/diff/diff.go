@@ -0,0 +1,300 @@
+// Package diff compares two export files entity by entity.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// Redacted replaces the values of secret fields in field changes.
+const Redacted = "<redacted>"
+
+// secretFields are not shown in field changes; only the fact that they changed.
+// _original contains the channel key.
+var secretFields = map[string]bool{
+	"api_key":        true,
+	"original_token": true,
+	"_original":      true,
+}
+
+// Report is the difference between two exports.
+type Report struct {
+	Old       FileInfo   `json:"old"`
+	New       FileInfo   `json:"new"`
+	Providers EntityDiff `json:"providers"` // Matched by original_id + multi_key_index
+	Masters   EntityDiff `json:"masters"`   // Matched by _source_user_id
+	Keys      EntityDiff `json:"keys"`      // Matched by _original_id
+}
+
+// FileInfo identifies one side of a diff.
+type FileInfo struct {
+	Path       string `json:"path"`
+	SourceType string `json:"source_type"`
+	ExportedAt string `json:"exported_at"`
+}
+
+// EntityDiff lists the changes of one entity kind.
+type EntityDiff struct {
+	Added    []Entity   `json:"added"`
+	Removed  []Entity   `json:"removed"`
+	Modified []Modified `json:"modified"`
+}
+
+// Entity identifies an exported entity.
+type Entity struct {
+	ID    string `json:"id"`    // Matching identity, e.g. "12#2"
+	Label string `json:"label"` // Human-readable name
+}
+
+// Modified is an entity present in both exports with different fields.
+type Modified struct {
+	Entity
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is a single changed field. A nil value means the field is
+// absent on that side.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Empty reports whether there are no changes.
+func (d EntityDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Empty reports whether the two exports contain the same entities.
+func (r *Report) Empty() bool {
+	return r.Providers.Empty() && r.Masters.Empty() && r.Keys.Empty()
+}
+
+// entry is an entity prepared for comparison.
+type entry struct {
+	id     string
+	order  [2]int // numeric identity, for sorting
+	label  string
+	fields map[string]interface{}
+}
+
+// Compare computes the difference from oldResult to newResult.
+func Compare(oldPath string, oldResult *schema.ExportResult, newPath string, newResult *schema.ExportResult) (*Report, error) {
+	report := &Report{
+		Old: fileInfo(oldPath, oldResult),
+		New: fileInfo(newPath, newResult),
+	}
+
+	oldProviders, oldMasters, oldKeys, err := resultEntries(oldResult)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", oldPath, err)
+	}
+	newProviders, newMasters, newKeys, err := resultEntries(newResult)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", newPath, err)
+	}
+
+	report.Providers = compareEntries(oldProviders, newProviders)
+	report.Masters = compareEntries(oldMasters, newMasters)
+	report.Keys = compareEntries(oldKeys, newKeys)
+
+	return report, nil
+}
+
+func fileInfo(path string, r *schema.ExportResult) FileInfo {
+	return FileInfo{
+		Path:       path,
+		SourceType: r.Source.Type,
+		ExportedAt: r.Source.ExportedAt.Format(time.RFC3339),
+	}
+}
+
+// resultEntries prepares the providers, masters and keys of r for comparison.
+// Entities sharing an identity cannot be matched, so they are an error.
+func resultEntries(r *schema.ExportResult) (providers, masters, keys []entry, err error) {
+	if providers, err = providerEntries(r.Data.Providers); err != nil {
+		return nil, nil, nil, err
+	}
+	if masters, err = masterEntries(r.Data.Masters); err != nil {
+		return nil, nil, nil, err
+	}
+	if keys, err = keyEntries(r.Data.Keys); err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, kind := range []struct {
+		name    string
+		entries []entry
+	}{
+		{"provider original_id#multi_key_index", providers},
+		{"master _source_user_id", masters},
+		{"key _original_id", keys},
+	} {
+		if dups := duplicateIDs(kind.entries); len(dups) > 0 {
+			return nil, nil, nil, fmt.Errorf("duplicate %s %s", kind.name, strings.Join(dups, ", "))
+		}
+	}
+	return providers, masters, keys, nil
+}
+
+// duplicateIDs returns the ids that occur more than once, in order of
+// first occurrence.
+func duplicateIDs(entries []entry) []string {
+	count := make(map[string]int, len(entries))
+	var dups []string
+	for _, e := range entries {
+		count[e.id]++
+		if count[e.id] == 2 {
+			dups = append(dups, e.id)
+		}
+	}
+	return dups
+}
+
+func providerEntries(providers []schema.Provider) ([]entry, error) {
+	entries := make([]entry, 0, len(providers))
+	for _, p := range providers {
+		e, err := newEntry(fmt.Sprintf("%d#%d", p.OriginalID, p.MultiKeyIndex), [2]int{p.OriginalID, p.MultiKeyIndex}, p.Name, p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func masterEntries(masters []schema.Master) ([]entry, error) {
+	entries := make([]entry, 0, len(masters))
+	for _, m := range masters {
+		e, err := newEntry(fmt.Sprintf("%d", m.SourceUserID), [2]int{m.SourceUserID}, m.Name, m)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func keyEntries(keys []schema.Key) ([]entry, error) {
+	entries := make([]entry, 0, len(keys))
+	for _, k := range keys {
+		label := fmt.Sprintf("token %d of %s", k.OriginalID, k.MasterRef)
+		e, err := newEntry(fmt.Sprintf("%d", k.OriginalID), [2]int{k.OriginalID}, label, k)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// newEntry flattens v into its top-level JSON fields, so changes are reported
+// under the same names as in the export file.
+func newEntry(id string, order [2]int, label string, v interface{}) (entry, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return entry{}, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return entry{}, err
+	}
+	return entry{id: id, order: order, label: label, fields: fields}, nil
+}
+
+// compareEntries matches entries by id. Results are sorted by identity.
+// Ids are unique on each side (see resultEntries).
+func compareEntries(oldEntries, newEntries []entry) EntityDiff {
+	oldByID := make(map[string]entry, len(oldEntries))
+	for _, e := range oldEntries {
+		oldByID[e.id] = e
+	}
+	newByID := make(map[string]entry, len(newEntries))
+	for _, e := range newEntries {
+		newByID[e.id] = e
+	}
+
+	d := EntityDiff{
+		Added:    []Entity{},
+		Removed:  []Entity{},
+		Modified: []Modified{},
+	}
+	for _, e := range sortedEntries(newByID) {
+		old, ok := oldByID[e.id]
+		if !ok {
+			d.Added = append(d.Added, Entity{ID: e.id, Label: e.label})
+			continue
+		}
+		if changes := compareFields(old.fields, e.fields); len(changes) > 0 {
+			d.Modified = append(d.Modified, Modified{
+				Entity:  Entity{ID: e.id, Label: e.label},
+				Changes: changes,
+			})
+		}
+	}
+	for _, e := range sortedEntries(oldByID) {
+		if _, ok := newByID[e.id]; !ok {
+			d.Removed = append(d.Removed, Entity{ID: e.id, Label: e.label})
+		}
+	}
+
+	return d
+}
+
+// sortedEntries returns the entries of m ordered by numeric identity.
+func sortedEntries(m map[string]entry) []entry {
+	entries := make([]entry, 0, len(m))
+	for _, e := range m {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].order, entries[j].order
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		return a[1] < b[1]
+	})
+	return entries
+}
+
+// compareFields returns the changed fields in name order.
+func compareFields(oldFields, newFields map[string]interface{}) []FieldChange {
+	names := make(map[string]bool)
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+	for _, name := range sorted {
+		oldValue, newValue := oldFields[name], newFields[name]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if secretFields[name] {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+		changes = append(changes, FieldChange{Field: name, Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+func redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return Redacted
+}
@@ -0,0 +1,197 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// exportOf builds an export with the given entities.
+func exportOf(providers []schema.Provider, masters []schema.Master, keys []schema.Key) *schema.ExportResult {
+	r := schema.NewExportResult()
+	r.Source = schema.Source{Type: "newapi", ExportedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	r.Data.Providers = providers
+	r.Data.Masters = masters
+	r.Data.Keys = keys
+	return r
+}
+
+func emptyDiff() EntityDiff {
+	return EntityDiff{Added: []Entity{}, Removed: []Entity{}, Modified: []Modified{}}
+}
+
+func TestCompareProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []schema.Provider
+		want     EntityDiff
+	}{
+		{
+			name: "unchanged",
+			old:  []schema.Provider{{OriginalID: 1, Name: "openai"}},
+			new:  []schema.Provider{{OriginalID: 1, Name: "openai"}},
+			want: emptyDiff(),
+		},
+		{
+			name: "added and removed",
+			old:  []schema.Provider{{OriginalID: 1, Name: "openai"}, {OriginalID: 2, Name: "claude"}},
+			new:  []schema.Provider{{OriginalID: 3, Name: "gemini"}, {OriginalID: 1, Name: "openai"}},
+			want: EntityDiff{
+				Added:    []Entity{{ID: "3#0", Label: "gemini"}},
+				Removed:  []Entity{{ID: "2#0", Label: "claude"}},
+				Modified: []Modified{},
+			},
+		},
+		{
+			name: "renamed channel matched by original ID",
+			old:  []schema.Provider{{OriginalID: 1, Name: "openai", Weight: 1}},
+			new:  []schema.Provider{{OriginalID: 1, Name: "openai-main", Weight: 1}},
+			want: EntityDiff{
+				Added:   []Entity{},
+				Removed: []Entity{},
+				Modified: []Modified{{
+					Entity:  Entity{ID: "1#0", Label: "openai-main"},
+					Changes: []FieldChange{{Field: "name", Old: "openai", New: "openai-main"}},
+				}},
+			},
+		},
+		{
+			name: "split providers matched by multi-key index",
+			old: []schema.Provider{
+				{OriginalID: 4, MultiKeyIndex: 1, Name: "multi", APIKey: "sk-1"},
+				{OriginalID: 4, MultiKeyIndex: 2, Name: "multi-2", APIKey: "sk-2"},
+			},
+			new: []schema.Provider{
+				{OriginalID: 4, MultiKeyIndex: 1, Name: "multi", APIKey: "sk-1b"},
+				{OriginalID: 4, MultiKeyIndex: 3, Name: "multi-3", APIKey: "sk-3"},
+			},
+			want: EntityDiff{
+				Added:   []Entity{{ID: "4#3", Label: "multi-3"}},
+				Removed: []Entity{{ID: "4#2", Label: "multi-2"}},
+				Modified: []Modified{{
+					Entity:  Entity{ID: "4#1", Label: "multi"},
+					Changes: []FieldChange{{Field: "api_key", Old: Redacted, New: Redacted}},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Compare("old.json", exportOf(tt.old, nil, nil), "new.json", exportOf(tt.new, nil, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(report.Providers, tt.want) {
+				t.Errorf("providers\n%+v\nwant\n%+v", report.Providers, tt.want)
+			}
+			if !report.Masters.Empty() || !report.Keys.Empty() {
+				t.Errorf("masters or keys changed: %+v %+v", report.Masters, report.Keys)
+			}
+		})
+	}
+}
+
+func TestCompareMasters(t *testing.T) {
+	old := []schema.Master{
+		{SourceUserID: 1, Name: "alice", Group: "default"},
+		{SourceUserID: 2, Name: "bob", Group: "default"},
+	}
+	new := []schema.Master{
+		{SourceUserID: 1, Name: "alice2", Group: "vip"},
+		{SourceUserID: 3, Name: "bob", Group: "default"}, // Same name, new user
+	}
+
+	report, err := Compare("old.json", exportOf(nil, old, nil), "new.json", exportOf(nil, new, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EntityDiff{
+		Added:   []Entity{{ID: "3", Label: "bob"}},
+		Removed: []Entity{{ID: "2", Label: "bob"}},
+		Modified: []Modified{{
+			Entity: Entity{ID: "1", Label: "alice2"},
+			Changes: []FieldChange{
+				{Field: "group", Old: "default", New: "vip"},
+				{Field: "name", Old: "alice", New: "alice2"},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(report.Masters, want) {
+		t.Errorf("masters\n%+v\nwant\n%+v", report.Masters, want)
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	expires := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := []schema.Key{
+		{OriginalID: 10, MasterRef: "alice", OriginalToken: "tok-a", Status: "active"},
+		{OriginalID: 11, MasterRef: "alice", OriginalToken: "tok-b", Status: "active"},
+	}
+	new := []schema.Key{
+		{OriginalID: 10, MasterRef: "alice", OriginalToken: "tok-a", Status: "disabled", ExpiresAt: &expires},
+		{OriginalID: 12, MasterRef: "bob", OriginalToken: "tok-b", Status: "active"}, // Same token, new ID
+	}
+
+	report, err := Compare("old.json", exportOf(nil, nil, old), "new.json", exportOf(nil, nil, new))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EntityDiff{
+		Added:   []Entity{{ID: "12", Label: "token 12 of bob"}},
+		Removed: []Entity{{ID: "11", Label: "token 11 of alice"}},
+		Modified: []Modified{{
+			Entity: Entity{ID: "10", Label: "token 10 of alice"},
+			Changes: []FieldChange{
+				{Field: "expires_at", Old: nil, New: "2025-01-01T00:00:00Z"},
+				{Field: "status", Old: "active", New: "disabled"},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(report.Keys, want) {
+		t.Errorf("keys\n%+v\nwant\n%+v", report.Keys, want)
+	}
+}
+
+func TestCompareDuplicateIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *schema.ExportResult
+		err    string
+	}{
+		{
+			name: "providers",
+			result: exportOf([]schema.Provider{
+				{OriginalID: 1, Name: "a"}, {OriginalID: 2, Name: "b"}, {OriginalID: 1, Name: "c"}, {OriginalID: 1, Name: "d"},
+			}, nil, nil),
+			err: "duplicate provider original_id#multi_key_index 1#0",
+		},
+		{
+			name:   "masters",
+			result: exportOf(nil, []schema.Master{{SourceUserID: 7, Name: "a"}, {SourceUserID: 7, Name: "b"}}, nil),
+			err:    "duplicate master _source_user_id 7",
+		},
+		{
+			name: "keys",
+			result: exportOf(nil, nil, []schema.Key{
+				{OriginalID: 3}, {OriginalID: 3}, {OriginalID: 4}, {OriginalID: 4},
+			}),
+			err: "duplicate key _original_id 3, 4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compare("old.json", exportOf(nil, nil, nil), "new.json", tt.result)
+			if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.Contains(err.Error(), "new.json") {
+				t.Errorf("got error %v, want %q in new.json", err, tt.err)
+			}
+
+			_, err = Compare("old.json", tt.result, "new.json", exportOf(nil, nil, nil))
+			if err == nil || !strings.Contains(err.Error(), "old.json") {
+				t.Errorf("got error %v, want duplicate in old.json", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Text, JSON and Markdown rendering of diff reports.
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{FormatText, FormatJSON, FormatMarkdown}
+
+// ValidFormat reports whether format is a supported output format.
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// section pairs an entity kind with its diff, in output order.
+type section struct {
+	title string
+	diff  EntityDiff
+}
+
+func (r *Report) sections() []section {
+	return []section{
+		{"Providers", r.Providers},
+		{"Masters", r.Masters},
+		{"Keys", r.Keys},
+	}
+}
+
+// Write renders the report to w in the given format.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		return r.writeText(w)
+	case FormatJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case FormatMarkdown:
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unsupported format: %s (available: %v)", format, Formats)
+	}
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- %s (%s, exported at %s)\n", r.Old.Path, r.Old.SourceType, r.Old.ExportedAt)
+	fmt.Fprintf(&b, "+++ %s (%s, exported at %s)\n", r.New.Path, r.New.SourceType, r.New.ExportedAt)
+
+	if r.Empty() {
+		b.WriteString("\nNo differences.\n")
+	}
+
+	for _, s := range r.sections() {
+		if s.diff.Empty() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s: %d added, %d removed, %d modified\n",
+			s.title, len(s.diff.Added), len(s.diff.Removed), len(s.diff.Modified))
+		for _, e := range s.diff.Added {
+			fmt.Fprintf(&b, "  + %s [%s]\n", e.Label, e.ID)
+		}
+		for _, e := range s.diff.Removed {
+			fmt.Fprintf(&b, "  - %s [%s]\n", e.Label, e.ID)
+		}
+		for _, m := range s.diff.Modified {
+			fmt.Fprintf(&b, "  ~ %s [%s]\n", m.Label, m.ID)
+			for _, c := range m.Changes {
+				fmt.Fprintf(&b, "      %s: %s -> %s\n", c.Field, formatValue(c.Old), formatValue(c.New))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# Export diff\n\n")
+	b.WriteString("| | File | Source | Exported at |\n")
+	b.WriteString("|---|------|--------|-------------|\n")
+	fmt.Fprintf(&b, "| Old | %s | %s | %s |\n", markdownCode(r.Old.Path), markdownEscape(r.Old.SourceType), r.Old.ExportedAt)
+	fmt.Fprintf(&b, "| New | %s | %s | %s |\n", markdownCode(r.New.Path), markdownEscape(r.New.SourceType), r.New.ExportedAt)
+
+	b.WriteString("\n## Summary\n\n")
+	b.WriteString("| Entity | Added | Removed | Modified |\n")
+	b.WriteString("|--------|-------|---------|----------|\n")
+	for _, s := range r.sections() {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d |\n",
+			s.title, len(s.diff.Added), len(s.diff.Removed), len(s.diff.Modified))
+	}
+
+	for _, s := range r.sections() {
+		if s.diff.Empty() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n", s.title)
+
+		if len(s.diff.Added) > 0 {
+			b.WriteString("\n### Added\n\n")
+			for _, e := range s.diff.Added {
+				fmt.Fprintf(&b, "- %s (`%s`)\n", markdownEscape(e.Label), e.ID)
+			}
+		}
+		if len(s.diff.Removed) > 0 {
+			b.WriteString("\n### Removed\n\n")
+			for _, e := range s.diff.Removed {
+				fmt.Fprintf(&b, "- %s (`%s`)\n", markdownEscape(e.Label), e.ID)
+			}
+		}
+		if len(s.diff.Modified) > 0 {
+			b.WriteString("\n### Modified\n")
+			for _, m := range s.diff.Modified {
+				fmt.Fprintf(&b, "\n**%s** (`%s`)\n\n", markdownEscape(m.Label), m.ID)
+				b.WriteString("| Field | Old | New |\n")
+				b.WriteString("|-------|-----|-----|\n")
+				for _, c := range m.Changes {
+					fmt.Fprintf(&b, "| %s | %s | %s |\n",
+						markdownCode(c.Field), markdownCell(c.Old), markdownCell(c.New))
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatValue renders a field value compactly as JSON. Absent fields are
+// shown as "(none)".
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	if s, ok := v.(string); ok && s == Redacted {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// markdownCell renders a value for a table cell.
+func markdownCell(v interface{}) string {
+	s := formatValue(v)
+	if v == nil || s == Redacted {
+		return "_" + strings.Trim(s, "()<>") + "_"
+	}
+	return markdownCode(s)
+}
+
+// markdownCode renders s as a code span that can be used in a table cell.
+// Spans containing backticks are delimited by double backticks.
+func markdownCode(s string) string {
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\n", " "), "|", "\\|")
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "*", "\\*", "_", "\\_", "`", "\\`", "|", "\\|",
+	"[", "\\[", "]", "\\]", "<", "\\<", ">", "\\>", "#", "\\#", "\n", " ",
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,99 @@
+package diff
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenReport compares two exports whose names and values need escaping
+// in Markdown.
+func goldenReport(t *testing.T) *Report {
+	t.Helper()
+
+	oldResult := exportOf(
+		[]schema.Provider{
+			{OriginalID: 1, Name: "openai", BaseURL: "https://api.openai.com", APIKey: "sk-old", Weight: 1},
+			{OriginalID: 2, Name: "claude_*main*", Weight: 1},
+		},
+		[]schema.Master{{SourceUserID: 1, Name: "alice", Group: "default"}},
+		[]schema.Key{{OriginalID: 10, MasterRef: "alice", OriginalToken: "tok", Status: "active"}},
+	)
+	newResult := exportOf(
+		[]schema.Provider{
+			{OriginalID: 1, Name: "openai|`prod`", BaseURL: "https://proxy.example/a|b", APIKey: "sk-new", Weight: 2},
+			{OriginalID: 3, MultiKeyIndex: 1, Name: "<gemini> [1]", Weight: 1},
+		},
+		[]schema.Master{{SourceUserID: 1, Name: "alice", Group: "default"}},
+		[]schema.Key{{OriginalID: 10, MasterRef: "alice", OriginalToken: "tok", Status: "active"}},
+	)
+	newResult.Source.Type = "oneapi"
+
+	report, err := Compare("old.json", oldResult, "exports/new_`1`.json", newResult)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestWriteGolden(t *testing.T) {
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{FormatText, "report.txt"},
+		{FormatJSON, "report.json"},
+		{FormatMarkdown, "report.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := goldenReport(t).Write(&buf, tt.format); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s output differs from %s (run go test -update to accept):\n%s", tt.format, path, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriteNoDifferences(t *testing.T) {
+	result := exportOf([]schema.Provider{{OriginalID: 1, Name: "openai"}}, nil, nil)
+	report, err := Compare("a.json", result, "b.json", result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a.json (newapi, exported at 2024-05-01T12:00:00Z)\n" +
+		"+++ b.json (newapi, exported at 2024-05-01T12:00:00Z)\n" +
+		"\nNo differences.\n"
+	if buf.String() != want {
+		t.Errorf("text output\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if err := report.Write(&buf, "html"); err == nil {
+		t.Error("unsupported format accepted")
+	}
+}
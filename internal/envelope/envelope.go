@@ -0,0 +1,363 @@
+// Package envelope encrypts export files at rest.
+//
+// An encrypted file starts with Magic, followed by a one-line JSON header and
+// the payload. The payload is encrypted with a random file key using
+// AES-256-GCM in fixed-size chunks, so files of any size can be encrypted and
+// decrypted as a stream. Each chunk's nonce carries its index and a final
+// flag, so reordered, dropped or truncated chunks are detected.
+//
+// The file key is wrapped once per recipient: with a key derived from a
+// passphrase (scrypt) or with a key agreed with an X25519 public key. Any one
+// matching identity can decrypt the file.
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Magic is the first line of every encrypted file.
+const Magic = "EZ-API-EXPORT-ENCRYPTED/1\n"
+
+const (
+	cipherName        = "aes-256-gcm"
+	defaultChunkSize  = 64 * 1024
+	maxChunkSize      = 16 * 1024 * 1024
+	maxHeaderSize     = 64 * 1024
+	fileKeySize       = 32
+	noncePrefixSize   = 7
+	chunkLengthSize   = 4
+	lastChunkFlag     = 1
+	gcmTagSize        = 16
+	maxChunkCiphertxt = maxChunkSize + gcmTagSize
+)
+
+// ErrNoIdentity is returned when none of the identities can unwrap the file key.
+var ErrNoIdentity = errors.New("no matching decryption key")
+
+// Header describes an encrypted file.
+type Header struct {
+	Cipher      string   `json:"cipher"`
+	ChunkSize   int      `json:"chunk_size"`
+	NoncePrefix []byte   `json:"nonce_prefix"`
+	Recipients  []Stanza `json:"recipients"`
+}
+
+// Stanza holds the file key wrapped for one recipient.
+type Stanza struct {
+	Type       string `json:"type"`                // "scrypt" or "x25519"
+	Salt       []byte `json:"salt,omitempty"`      // scrypt salt
+	LogN       int    `json:"log_n,omitempty"`     // scrypt cost parameter
+	Ephemeral  []byte `json:"ephemeral,omitempty"` // x25519 ephemeral public key
+	Recipient  []byte `json:"recipient,omitempty"` // x25519 recipient public key
+	WrappedKey []byte `json:"wrapped_key"`         // file key, AES-256-GCM sealed
+}
+
+// Recipient can wrap a file key.
+type Recipient interface {
+	Wrap(fileKey []byte) (Stanza, error)
+}
+
+// Identity can unwrap a file key. Unwrap returns ErrNoIdentity for stanzas
+// meant for another identity.
+type Identity interface {
+	Unwrap(s Stanza) ([]byte, error)
+}
+
+// IsEncrypted reports whether data starts like an encrypted file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Encrypt writes the header to w and returns a writer that encrypts the
+// payload. Close must be called to write the final chunk; it does not close w.
+func Encrypt(w io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	fileKey := make([]byte, fileKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+
+	header := Header{
+		Cipher:      cipherName,
+		ChunkSize:   defaultChunkSize,
+		NoncePrefix: make([]byte, noncePrefixSize),
+	}
+	if _, err := rand.Read(header.NoncePrefix); err != nil {
+		return nil, err
+	}
+	for _, r := range recipients {
+		stanza, err := r.Wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		header.Recipients = append(header.Recipients, stanza)
+	}
+
+	headerLine, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	prefix := append([]byte(Magic), append(headerLine, '\n')...)
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:           w,
+		aead:        aead,
+		aad:         headerDigest(prefix),
+		noncePrefix: header.NoncePrefix,
+		buf:         make([]byte, 0, header.ChunkSize),
+	}, nil
+}
+
+// Decrypt reads the header from r, unwraps the file key with the first
+// matching identity and returns a reader of the decrypted payload.
+func Decrypt(r io.Reader, identities ...Identity) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != Magic {
+		return nil, errors.New("not an encrypted export file")
+	}
+	headerLine, err := readLine(br, maxHeaderSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	var header Header
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if header.Cipher != cipherName {
+		return nil, fmt.Errorf("unsupported cipher: %s", header.Cipher)
+	}
+	if header.ChunkSize <= 0 || header.ChunkSize > maxChunkSize || len(header.NoncePrefix) != noncePrefixSize {
+		return nil, errors.New("invalid header: bad chunk parameters")
+	}
+
+	fileKey, err := unwrap(header.Recipients, identities)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := append([]byte(Magic), append(headerLine, '\n')...)
+	return &decryptReader{
+		r:           br,
+		aead:        aead,
+		aad:         headerDigest(prefix),
+		noncePrefix: header.NoncePrefix,
+		maxChunk:    header.ChunkSize + gcmTagSize,
+	}, nil
+}
+
+// unwrap tries every identity on every stanza.
+func unwrap(stanzas []Stanza, identities []Identity) ([]byte, error) {
+	for _, id := range identities {
+		for _, s := range stanzas {
+			key, err := id.Unwrap(s)
+			if errors.Is(err, ErrNoIdentity) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(key) != fileKeySize {
+				return nil, errors.New("invalid file key")
+			}
+			return key, nil
+		}
+	}
+	return nil, ErrNoIdentity
+}
+
+// encryptWriter seals the payload chunk by chunk.
+type encryptWriter struct {
+	w           io.Writer
+	aead        cipher.AEAD
+	aad         []byte
+	noncePrefix []byte
+	counter     uint32
+	buf         []byte
+	closed      bool
+	err         error
+}
+
+// Write buffers p and writes every full chunk. The last chunk is only known
+// at Close, so a full buffer is flushed when more data arrives.
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.closed {
+		return 0, errors.New("write after close")
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		if len(e.buf) == cap(e.buf) {
+			if e.err = e.flush(false); e.err != nil {
+				return 0, e.err
+			}
+		}
+		free := cap(e.buf) - len(e.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		e.buf = append(e.buf, p[:free]...)
+		p = p[free:]
+	}
+	return n, nil
+}
+
+// Close writes the final chunk.
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+	if e.err == nil {
+		e.err = e.flush(true)
+	}
+	return e.err
+}
+
+func (e *encryptWriter) flush(last bool) error {
+	if e.counter == ^uint32(0) {
+		return errors.New("payload too large")
+	}
+	sealed := e.aead.Seal(nil, chunkNonce(e.noncePrefix, e.counter, last), e.buf, e.aad)
+	e.counter++
+	e.buf = e.buf[:0]
+
+	var length [chunkLengthSize]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+	if _, err := e.w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptReader opens the payload chunk by chunk.
+type decryptReader struct {
+	r           *bufio.Reader
+	aead        cipher.AEAD
+	aad         []byte
+	noncePrefix []byte
+	maxChunk    int
+	counter     uint32
+	plain       []byte
+	done        bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk. A chunk is final if it opens with
+// the final flag set; data after it, or EOF before it, is an error.
+func (d *decryptReader) next() error {
+	var length [chunkLengthSize]byte
+	if _, err := io.ReadFull(d.r, length[:]); err != nil {
+		return errors.New("encrypted payload is truncated")
+	}
+	size := int(binary.BigEndian.Uint32(length[:]))
+	if size < gcmTagSize || size > d.maxChunk || size > maxChunkCiphertxt {
+		return errors.New("encrypted payload is corrupted")
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return errors.New("encrypted payload is truncated")
+	}
+
+	plain, err := d.aead.Open(nil, chunkNonce(d.noncePrefix, d.counter, false), sealed, d.aad)
+	if err != nil {
+		plain, err = d.aead.Open(nil, chunkNonce(d.noncePrefix, d.counter, true), sealed, d.aad)
+		if err != nil {
+			return errors.New("encrypted payload is corrupted or was modified")
+		}
+		d.done = true
+		if _, err := d.r.Peek(1); err != io.EOF {
+			return errors.New("unexpected data after encrypted payload")
+		}
+	}
+	d.counter++
+	d.plain = plain
+	return nil
+}
+
+// chunkNonce builds the nonce of chunk i: prefix || big-endian i || final flag.
+func chunkNonce(prefix []byte, i uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+4+1)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, i)
+	if last {
+		return append(nonce, lastChunkFlag)
+	}
+	return append(nonce, 0)
+}
+
+// headerDigest binds every chunk to the exact header, so recipients or
+// parameters cannot be swapped.
+func headerDigest(prefix []byte) []byte {
+	sum := sha256.Sum256(prefix)
+	return sum[:]
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readLine reads up to and excluding '\n', failing on lines longer than max.
+func readLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > max {
+			return nil, errors.New("line too long")
+		}
+		if err == nil {
+			return line[:len(line)-1], nil
+		}
+		if err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+}
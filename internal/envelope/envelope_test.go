@@ -0,0 +1,284 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// encrypt encrypts plaintext for recipients, writing it in uneven pieces to
+// exercise the chunk buffering.
+func encrypt(t *testing.T, plaintext []byte, recipients ...Recipient) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := Encrypt(&buf, recipients...)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	for p, step := plaintext, 1; len(p) > 0; step = step*3 + 1 {
+		n := step
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decrypt decrypts data and reads the whole payload.
+func decrypt(data []byte, identities ...Identity) ([]byte, error) {
+	r, err := Decrypt(bytes.NewReader(data), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// splitChunks splits an encrypted file into the magic and header prefix and
+// the chunks, each with its length prefix.
+func splitChunks(t *testing.T, data []byte) (prefix []byte, chunks [][]byte) {
+	t.Helper()
+
+	i := bytes.IndexByte(data[len(Magic):], '\n')
+	if i < 0 {
+		t.Fatal("no header line")
+	}
+	end := len(Magic) + i + 1
+	prefix, rest := data[:end], data[end:]
+	for len(rest) > 0 {
+		size := chunkLengthSize + int(binary.BigEndian.Uint32(rest))
+		chunks = append(chunks, rest[:size])
+		rest = rest[size:]
+	}
+	return prefix, chunks
+}
+
+func join(prefix []byte, chunks ...[]byte) []byte {
+	out := append([]byte(nil), prefix...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newPassphrase(t *testing.T, passphrase string) (Recipient, Identity) {
+	t.Helper()
+	r, err := NewPassphraseRecipient(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := ParseIdentity(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, id
+}
+
+func newX25519(t *testing.T) (Recipient, Identity) {
+	t.Helper()
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Round-trip the key strings as the CLI does
+	r, err := ParseX25519Recipient(id.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseIdentity(id.String() + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, parsed
+}
+
+func TestRoundTripPassphrase(t *testing.T) {
+	r, id := newPassphrase(t, "correct horse battery staple")
+
+	sizes := map[string]int{
+		"empty":            0,
+		"one byte":         1,
+		"below boundary":   defaultChunkSize - 1,
+		"on boundary":      defaultChunkSize,
+		"above boundary":   defaultChunkSize + 1,
+		"two chunks exact": 2 * defaultChunkSize,
+		"several chunks":   3*defaultChunkSize + 100,
+	}
+	for name, size := range sizes {
+		t.Run(name, func(t *testing.T) {
+			plaintext := randomBytes(t, size)
+			data := encrypt(t, plaintext, r)
+
+			if !IsEncrypted(data) {
+				t.Fatal("IsEncrypted = false")
+			}
+			if size >= 16 && bytes.Contains(data, plaintext) {
+				t.Fatal("ciphertext contains the plaintext")
+			}
+
+			got, err := decrypt(data, id)
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("got %d bytes, want %d", len(got), len(plaintext))
+			}
+
+			_, chunks := splitChunks(t, data)
+			wantChunks := (size + defaultChunkSize - 1) / defaultChunkSize
+			if wantChunks == 0 {
+				wantChunks = 1
+			}
+			if len(chunks) != wantChunks {
+				t.Errorf("got %d chunks, want %d", len(chunks), wantChunks)
+			}
+		})
+	}
+}
+
+func TestRoundTripMultipleRecipients(t *testing.T) {
+	pr, pid := newPassphrase(t, "shared secret")
+	xr1, xid1 := newX25519(t)
+	xr2, xid2 := newX25519(t)
+
+	plaintext := randomBytes(t, defaultChunkSize+10)
+	data := encrypt(t, plaintext, pr, xr1, xr2)
+
+	for name, id := range map[string]Identity{"passphrase": pid, "x25519 first": xid1, "x25519 second": xid2} {
+		t.Run(name, func(t *testing.T) {
+			got, err := decrypt(data, id)
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatal("plaintext mismatch")
+			}
+		})
+	}
+
+	// Any matching identity among several is enough
+	_, other := newX25519(t)
+	if _, err := decrypt(data, other, xid2); err != nil {
+		t.Fatalf("decrypt with one matching identity: %v", err)
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	pr, _ := newPassphrase(t, "right")
+	xr, _ := newX25519(t)
+	_, wrongPassphrase := newPassphrase(t, "wrong")
+	_, wrongX25519 := newX25519(t)
+
+	tests := []struct {
+		name      string
+		recipient Recipient
+		identity  Identity
+	}{
+		{"wrong passphrase", pr, wrongPassphrase},
+		{"wrong x25519 key", xr, wrongX25519},
+		{"x25519 key for passphrase file", pr, wrongX25519},
+		{"passphrase for x25519 file", xr, wrongPassphrase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encrypt(t, []byte("secret"), tt.recipient)
+			if _, err := decrypt(data, tt.identity); !errors.Is(err, ErrNoIdentity) {
+				t.Fatalf("got %v, want ErrNoIdentity", err)
+			}
+		})
+	}
+}
+
+func TestModifiedPayload(t *testing.T) {
+	r, id := newPassphrase(t, "passphrase")
+	data := encrypt(t, randomBytes(t, 3*defaultChunkSize+100), r)
+	prefix, chunks := splitChunks(t, data)
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+
+	flip := func(b []byte, i int) []byte {
+		out := append([]byte(nil), b...)
+		out[i] ^= 0x01
+		return out
+	}
+
+	tests := map[string][]byte{
+		"flipped ciphertext byte": join(prefix, chunks[0], flip(chunks[1], 100), chunks[2], chunks[3]),
+		"flipped tag byte":        join(prefix, chunks[0], chunks[1], chunks[2], flip(chunks[3], len(chunks[3])-1)),
+		"flipped header byte":     join(flip(prefix, len(prefix)-3), chunks...),
+		"reordered chunks":        join(prefix, chunks[1], chunks[0], chunks[2], chunks[3]),
+		"duplicated chunk":        join(prefix, chunks[0], chunks[0], chunks[1], chunks[2], chunks[3]),
+		"dropped middle chunk":    join(prefix, chunks[0], chunks[2], chunks[3]),
+		"dropped final chunk":     join(prefix, chunks[0], chunks[1], chunks[2]),
+		"truncated final chunk":   join(prefix, chunks[0], chunks[1], chunks[2], chunks[3][:len(chunks[3])-1]),
+		"truncated length":        join(prefix, chunks[0], chunks[1], chunks[2], chunks[3][:2]),
+		"extended with chunk":     join(prefix, chunks[0], chunks[1], chunks[2], chunks[3], chunks[1]),
+		"extended with byte":      append(join(prefix, chunks...), 0),
+		"final chunk moved first": join(prefix, chunks[3], chunks[0], chunks[1], chunks[2]),
+	}
+	for name, modified := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decrypt(modified, id); err == nil {
+				t.Fatal("decrypt succeeded")
+			}
+		})
+	}
+
+	// Chunks from another file encrypted with the same passphrase
+	other := encrypt(t, randomBytes(t, 3*defaultChunkSize+100), r)
+	_, otherChunks := splitChunks(t, other)
+	if _, err := decrypt(join(prefix, chunks[0], otherChunks[1], chunks[2], chunks[3]), id); err == nil {
+		t.Fatal("decrypt succeeded with a chunk spliced from another file")
+	}
+}
+
+func TestEmptyPayloadTruncated(t *testing.T) {
+	r, id := newPassphrase(t, "passphrase")
+	data := encrypt(t, nil, r)
+	prefix, chunks := splitChunks(t, data)
+	if len(chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(chunks))
+	}
+	if _, err := decrypt(prefix, id); err == nil {
+		t.Fatal("decrypt succeeded without the final chunk")
+	}
+}
+
+func TestNotEncrypted(t *testing.T) {
+	_, id := newPassphrase(t, "passphrase")
+	if IsEncrypted([]byte(`{"version":"1.0.0"}`)) {
+		t.Fatal("IsEncrypted = true for JSON")
+	}
+	if _, err := decrypt([]byte(`{"version":"1.0.0"}`), id); err == nil {
+		t.Fatal("decrypt succeeded on plain JSON")
+	}
+}
+
+func TestNoRecipients(t *testing.T) {
+	if _, err := Encrypt(io.Discard); err == nil {
+		t.Fatal("Encrypt without recipients succeeded")
+	}
+	if _, err := NewPassphraseRecipient(""); err == nil {
+		t.Fatal("empty passphrase accepted")
+	}
+}
@@ -0,0 +1,260 @@
+// Passphrase and X25519 recipients and identities.
+
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Stanza types.
+const (
+	TypeScrypt = "scrypt"
+	TypeX25519 = "x25519"
+)
+
+// Key string prefixes.
+const (
+	PublicKeyPrefix = "x25519:"
+	SecretKeyPrefix = "x25519-secret:"
+)
+
+const (
+	scryptLogN    = 15 // N = 32768, about 100ms
+	scryptMaxLogN = 22 // refuse headers asking for more work than this
+	scryptR       = 8
+	scryptP       = 1
+	scryptSalt    = 16
+	hkdfInfo      = "ez-api exporter x25519"
+)
+
+// wrapNonce is the nonce for wrapping file keys. Every wrapping key is used
+// once (fresh salt or ephemeral key), so a fixed nonce is safe.
+var wrapNonce = make([]byte, 12)
+
+// PassphraseRecipient wraps file keys with a key derived from a passphrase.
+type PassphraseRecipient struct {
+	passphrase []byte
+}
+
+// NewPassphraseRecipient creates a passphrase recipient.
+func NewPassphraseRecipient(passphrase string) (*PassphraseRecipient, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	return &PassphraseRecipient{passphrase: []byte(passphrase)}, nil
+}
+
+// Wrap implements Recipient.
+func (r *PassphraseRecipient) Wrap(fileKey []byte) (Stanza, error) {
+	salt := make([]byte, scryptSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return Stanza{}, err
+	}
+	kek, err := scrypt.Key(r.passphrase, salt, 1<<scryptLogN, scryptR, scryptP, 32)
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapped, err := seal(kek, fileKey)
+	if err != nil {
+		return Stanza{}, err
+	}
+	return Stanza{Type: TypeScrypt, Salt: salt, LogN: scryptLogN, WrappedKey: wrapped}, nil
+}
+
+// PassphraseIdentity unwraps file keys wrapped with a passphrase.
+type PassphraseIdentity struct {
+	passphrase []byte
+}
+
+// Unwrap implements Identity.
+func (id *PassphraseIdentity) Unwrap(s Stanza) ([]byte, error) {
+	if s.Type != TypeScrypt {
+		return nil, ErrNoIdentity
+	}
+	if s.LogN <= 0 || s.LogN > scryptMaxLogN {
+		return nil, fmt.Errorf("unsupported scrypt work factor: %d", s.LogN)
+	}
+	kek, err := scrypt.Key(id.passphrase, s.Salt, 1<<s.LogN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := open(kek, s.WrappedKey)
+	if err != nil {
+		// Wrong passphrase; another stanza may still match
+		return nil, ErrNoIdentity
+	}
+	return fileKey, nil
+}
+
+// X25519Recipient wraps file keys for the holder of an X25519 secret key.
+type X25519Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// ParseX25519Recipient parses a public key string ("x25519:<base64>").
+func ParseX25519Recipient(s string) (*X25519Recipient, error) {
+	raw, err := decodeKey(s, PublicKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return &X25519Recipient{key: key}, nil
+}
+
+// String returns the public key string.
+func (r *X25519Recipient) String() string {
+	return PublicKeyPrefix + base64.RawStdEncoding.EncodeToString(r.key.Bytes())
+}
+
+// Wrap implements Recipient.
+func (r *X25519Recipient) Wrap(fileKey []byte) (Stanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return Stanza{}, err
+	}
+	shared, err := ephemeral.ECDH(r.key)
+	if err != nil {
+		return Stanza{}, err
+	}
+	kek, err := x25519KEK(shared, ephemeral.PublicKey().Bytes(), r.key.Bytes())
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapped, err := seal(kek, fileKey)
+	if err != nil {
+		return Stanza{}, err
+	}
+	return Stanza{
+		Type:       TypeX25519,
+		Ephemeral:  ephemeral.PublicKey().Bytes(),
+		Recipient:  r.key.Bytes(),
+		WrappedKey: wrapped,
+	}, nil
+}
+
+// X25519Identity is an X25519 secret key.
+type X25519Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// GenerateX25519Identity creates a new random secret key.
+func GenerateX25519Identity() (*X25519Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &X25519Identity{key: key}, nil
+}
+
+// String returns the secret key string ("x25519-secret:<base64>").
+func (id *X25519Identity) String() string {
+	return SecretKeyPrefix + base64.RawStdEncoding.EncodeToString(id.key.Bytes())
+}
+
+// Recipient returns the public key of the identity.
+func (id *X25519Identity) Recipient() *X25519Recipient {
+	return &X25519Recipient{key: id.key.PublicKey()}
+}
+
+// Unwrap implements Identity.
+func (id *X25519Identity) Unwrap(s Stanza) ([]byte, error) {
+	if s.Type != TypeX25519 || string(s.Recipient) != string(id.key.PublicKey().Bytes()) {
+		return nil, ErrNoIdentity
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(s.Ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+	shared, err := id.key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := x25519KEK(shared, s.Ephemeral, s.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := open(kek, s.WrappedKey)
+	if err != nil {
+		return nil, errors.New("failed to unwrap file key: header was modified")
+	}
+	return fileKey, nil
+}
+
+// ParseIdentity parses a decryption key: an X25519 secret key string, or
+// otherwise a passphrase. Surrounding whitespace is ignored, so keys can be
+// read from files with a trailing newline.
+func ParseIdentity(s string) (Identity, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, SecretKeyPrefix) {
+		raw, err := decodeKey(s, SecretKeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ecdh.X25519().NewPrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret key: %w", err)
+		}
+		return &X25519Identity{key: key}, nil
+	}
+	if s == "" {
+		return nil, errors.New("empty decryption key")
+	}
+	return &PassphraseIdentity{passphrase: []byte(s)}, nil
+}
+
+// x25519KEK derives a wrapping key from an X25519 shared secret, bound to
+// the ephemeral and recipient public keys.
+func x25519KEK(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	return hkdf.Key(sha256.New, shared, salt, hkdfInfo, 32)
+}
+
+func decodeKey(s, prefix string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("key must start with %q", prefix)
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key encoding: %w", err)
+	}
+	return raw, nil
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, wrapNonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, wrapNonce, ciphertext, nil)
+}
@@ -0,0 +1,217 @@
+// Package redact rewrites secrets in exported entities, so export files can
+// be shared for review without exposing API keys and tokens.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// maskKeep is the number of characters kept at each end of a masked secret.
+// Secrets shorter than 3*maskKeep are masked completely.
+const maskKeep = 4
+
+// hashPrefix marks hashed secrets.
+const hashPrefix = "hmac-sha256:"
+
+// freeFormSecretFields are the source fields holding free-form JSON that may
+// contain secrets (e.g. Authorization headers or upstream API keys in request
+// parameters). Their values are redacted as a whole, both in _original
+// backups and when quoted in warnings.
+var freeFormSecretFields = []string{"setting", "param_override", "header_override", "other_info"}
+
+// warningSecretFields are the source fields whose values, when quoted in
+// warnings, may hold secrets. Warnings quote only the unmapped entries of
+// the One API config, which are redacted as a whole as well.
+var warningSecretFields = append([]string{"config"}, freeFormSecretFields...)
+
+// Redactor rewrites secrets according to a redaction mode.
+type Redactor struct {
+	mode string
+	salt []byte
+}
+
+// New creates a redactor. mode is one of schema.RedactionModes; hash mode
+// requires a non-empty salt, so hashes cannot be reversed by hashing
+// candidate keys.
+func New(mode, salt string) (*Redactor, error) {
+	switch mode {
+	case schema.RedactMask, schema.RedactDrop:
+	case schema.RedactHash:
+		if salt == "" {
+			return nil, fmt.Errorf("redaction mode %q requires a salt", mode)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported redaction mode: %s (available: %v)", mode, schema.RedactionModes)
+	}
+	return &Redactor{mode: mode, salt: []byte(salt)}, nil
+}
+
+// Mode returns the redaction mode.
+func (r *Redactor) Mode() string {
+	return r.mode
+}
+
+// Secret redacts a single secret. Empty secrets stay empty.
+func (r *Redactor) Secret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	switch r.mode {
+	case schema.RedactMask:
+		if len(secret) < 3*maskKeep {
+			return strings.Repeat("*", len(secret))
+		}
+		return secret[:maskKeep] + strings.Repeat("*", len(secret)-2*maskKeep) + secret[len(secret)-maskKeep:]
+	case schema.RedactHash:
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(secret))
+		return hashPrefix + hex.EncodeToString(mac.Sum(nil))
+	default:
+		return ""
+	}
+}
+
+// Original redacts the secret fields of an _original backup blob: the keys,
+// the credentials in the channel config and the free-form fields that may
+// hold secrets. Blobs that are not JSON objects are dropped, since their
+// secrets cannot be located.
+func (r *Redactor) Original(original json.RawMessage) json.RawMessage {
+	if len(original) == 0 {
+		return original
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(original, &fields); err != nil {
+		return nil
+	}
+
+	for _, name := range schema.BackupSecretFields {
+		if value, ok := fields[name].(string); ok {
+			fields[name] = r.secretLines(value)
+		}
+	}
+	if config, ok := fields["config"].(string); ok {
+		fields["config"] = r.config(config)
+	}
+	for _, name := range freeFormSecretFields {
+		switch value := fields[name].(type) {
+		case nil:
+		case string:
+			fields[name] = r.Secret(value)
+		default:
+			// Redacted as JSON text, like warning values
+			data, _ := json.Marshal(value)
+			fields[name] = r.Secret(string(data))
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// secretLines redacts each of several newline-separated secrets.
+func (r *Redactor) secretLines(value string) string {
+	if r.mode == schema.RedactDrop {
+		return ""
+	}
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		lines[i] = r.Secret(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// config redacts the credentials in a channel config JSON. Configs that are
+// not JSON objects are dropped, since their credentials cannot be located;
+// credentials that are not strings are removed.
+func (r *Redactor) config(config string) string {
+	if strings.TrimSpace(config) == "" {
+		return config
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(config), &fields); err != nil {
+		return ""
+	}
+	for _, name := range schema.BackupConfigSecretFields {
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if secret, ok := value.(string); ok {
+			fields[name] = r.Secret(secret)
+		} else {
+			delete(fields, name)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Sink wraps a schema.Sink and redacts secrets before passing entities on.
+type Sink struct {
+	schema.Sink
+	redactor *Redactor
+}
+
+// NewSink returns a sink that redacts entities and records the redaction
+// mode in the source information before passing them to sink.
+func NewSink(sink schema.Sink, redactor *Redactor) *Sink {
+	return &Sink{Sink: sink, redactor: redactor}
+}
+
+// SetSource sets the source information, marking it as redacted.
+func (s *Sink) SetSource(src schema.Source) {
+	src.Redaction = s.redactor.Mode()
+	s.Sink.SetSource(src)
+}
+
+// AddProvider redacts the API key and original backup of a provider.
+func (s *Sink) AddProvider(p schema.Provider) {
+	p.APIKey = s.redactor.Secret(p.APIKey)
+	p.Original = s.redactor.Original(p.Original)
+	s.Sink.AddProvider(p)
+}
+
+// AddWarning redacts the original value quoted by warnings about fields that
+// may hold secrets. Values that are not strings are redacted as JSON text.
+func (s *Sink) AddWarning(w schema.Warning) {
+	for _, name := range warningSecretFields {
+		if w.Field != name || len(w.Value) == 0 {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(w.Value, &value); err != nil {
+			value = string(w.Value)
+		}
+		w.Value = schema.WarningValue(s.redactor.Secret(value))
+	}
+	s.Sink.AddWarning(w)
+}
+
+// AddRedemption redacts a redemption code.
+func (s *Sink) AddRedemption(c schema.Redemption) {
+	c.Code = s.redactor.Secret(c.Code)
+	s.Sink.AddRedemption(c)
+}
+
+// AddKey redacts the token of a key.
+func (s *Sink) AddKey(k schema.Key) {
+	k.OriginalToken = s.redactor.Secret(k.OriginalToken)
+	s.Sink.AddKey(k)
+}
@@ -8,19 +8,89 @@ import (
 	"time"
 )
 
+// FormatVersion is the version of the intermediate format written by this
+// exporter. It changes whenever the JSON structure changes.
+const FormatVersion = "1.10.0"
+
+// CompatibleVersions lists the format versions this exporter can read.
+// Later versions only add optional fields, so older files remain valid.
+var CompatibleVersions = []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0", "1.5.0", "1.6.0", "1.7.0", "1.8.0", "1.9.0", FormatVersion}
+
 // ExportResult represents the complete export output.
 type ExportResult struct {
 	Version  string   `json:"version"`            // Schema version, e.g., "1.0.0"
 	Source   Source   `json:"source"`             // Source system information
 	Data     Data     `json:"data"`               // Exported data
-	Warnings []string `json:"warnings,omitempty"` // Export warnings
+	Warnings []string `json:"warnings,omitempty"` // Export warning messages
+
+	// Structured export warnings, in the same order as Warnings
+	WarningDetails []Warning `json:"warning_details,omitempty"`
 }
 
 // Source represents the source system information.
 type Source struct {
 	Type       string    `json:"type"`        // Source type, e.g., "newapi"
-	Version    string    `json:"version"`     // Source version if detectable
+	Version    string    `json:"version"`     // Source version or version range if detectable, else "unknown"
 	ExportedAt time.Time `json:"exported_at"` // Export timestamp
+
+	// Consistent snapshot time, set when all tables were read in one
+	// read-only transaction
+	SnapshotAt *time.Time `json:"snapshot_at,omitempty"`
+
+	// Source columns absent from the database ("table.column"), e.g. in
+	// databases of older releases; the fields they hold are exported empty
+	MissingColumns []string `json:"missing_columns,omitempty"`
+
+	// Set for incremental exports, which only contain changes
+	Delta *Delta `json:"delta,omitempty"`
+
+	// Secret redaction mode (mask/hash/drop). Redacted exports cannot be
+	// imported, since API keys and tokens are no longer usable.
+	Redaction string `json:"redaction,omitempty"`
+
+	// Policy used for the _original channel backups (full/scrubbed/
+	// unmappable/none)
+	Backup string `json:"backup,omitempty"`
+
+	// Quota units per balance unit, used for the master balances
+	QuotaPerUnit float64 `json:"quota_per_unit,omitempty"`
+}
+
+// Secret redaction modes.
+const (
+	RedactMask = "mask" // Keep a short prefix and suffix
+	RedactHash = "hash" // Replace with a salted HMAC
+	RedactDrop = "drop" // Remove
+)
+
+// RedactionModes lists the supported redaction modes.
+var RedactionModes = []string{RedactMask, RedactHash, RedactDrop}
+
+// Backup policies for the _original channel backup.
+const (
+	BackupFull       = "full"       // Complete channel row, including key material
+	BackupScrubbed   = "scrubbed"   // Complete channel row without key material
+	BackupUnmappable = "unmappable" // Only the fields that cannot be mapped, without key material
+	BackupNone       = "none"       // No backup
+)
+
+// BackupPolicies lists the supported backup policies.
+var BackupPolicies = []string{BackupFull, BackupScrubbed, BackupUnmappable, BackupNone}
+
+// BackupSecretFields are the fields of a channel row holding key material,
+// removed from _original backups by every policy but full. Channel keys may
+// hold several newline-separated keys.
+var BackupSecretFields = []string{"key"}
+
+// BackupConfigSecretFields are the fields of the channel config JSON (the
+// One API config field) holding credentials: AWS access keys and the Vertex
+// AI service account.
+var BackupConfigSecretFields = []string{"ak", "sk", "vertex_ai_adc"}
+
+// Delta marks an incremental export. Data holds only entities created or
+// changed at or after Since; Tombstones lists entities deleted since then.
+type Delta struct {
+	Since time.Time `json:"since"` // Start of the change window
 }
 
 // Data contains all exported entities.
@@ -29,6 +99,19 @@ type Data struct {
 	Masters   []Master   `json:"masters,omitempty"`
 	Keys      []Key      `json:"keys,omitempty"`
 	Bindings  []Binding  `json:"bindings,omitempty"`
+
+	// Priority tiers per (group, model), from abilities (optional,
+	// --include-abilities)
+	FailoverGroups []FailoverGroup `json:"failover_groups,omitempty"`
+
+	// Prepaid quota codes (optional, --include-redemptions)
+	Redemptions []Redemption `json:"redemptions,omitempty"`
+
+	// Source users with admin rights
+	Administrators []Administrator `json:"administrators,omitempty"`
+
+	// Entities deleted since the previous export (incremental exports only)
+	Tombstones []Tombstone `json:"tombstones,omitempty"`
 }
 
 // Provider represents an EZ-API provider (mapped from New API channel).
@@ -46,15 +129,60 @@ type Provider struct {
 	Status       string   `json:"status"`               // active/disabled
 	AutoBan      bool     `json:"auto_ban"`             // Auto ban on failure
 
+	// Why and when the key was disabled (from New API multi-key status)
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+
+	// Model renames (from New API model_mapping)
+	ModelAliases []ModelAlias `json:"model_aliases,omitempty"` // Public model -> upstream model
+
+	// Settings packed into the source key or free-form fields (Azure, AWS,
+	// Vertex AI, Cloudflare)
+	Options *ProviderOptions `json:"options,omitempty"`
+
 	// Multi-key tracking
 	IsMultiKey    bool   `json:"is_multi_key,omitempty"`    // Was this from a multi-key channel
 	MultiKeyIndex int    `json:"multi_key_index,omitempty"` // Index in multi-key split (1-based)
-	OriginalName  string `json:"original_name,omitempty"`   // Original channel name before split
+	OriginalName  string `json:"original_name,omitempty"`   // Channel name, if split or renamed
 
-	// Original data backup (for fields that cannot be mapped)
+	// Original data backup (for fields that cannot be mapped). Providers
+	// split from one channel share a backup, so only the first carries it.
 	Original json.RawMessage `json:"_original,omitempty"`
 }
 
+// ModelAlias represents a model rename applied by a provider.
+// Requests for Model are forwarded upstream as UpstreamModel.
+type ModelAlias struct {
+	Model         string `json:"model"`          // Public model name requested by clients
+	UpstreamModel string `json:"upstream_model"` // Model name sent to the upstream provider
+}
+
+// Provider credential types, telling how api_key is used.
+const (
+	CredentialAPIKey         = "api_key"         // Sent as is
+	CredentialAccessKey      = "access_key"      // AWS secret access key, with options.access_key_id
+	CredentialServiceAccount = "service_account" // Google service account JSON key
+)
+
+// ProviderOptions holds provider settings that source systems store next to
+// the key. Secrets stay in Provider.APIKey.
+type ProviderOptions struct {
+	CredentialType string        `json:"credential_type,omitempty"` // How api_key is used
+	Region         string        `json:"region,omitempty"`          // AWS or Vertex AI region
+	ModelRegions   []ModelRegion `json:"model_regions,omitempty"`   // Vertex AI per-model regions
+	APIVersion     string        `json:"api_version,omitempty"`     // Azure OpenAI API version
+	Deployment     string        `json:"deployment,omitempty"`      // Azure OpenAI deployment
+	Project        string        `json:"project,omitempty"`         // Vertex AI project ID
+	AccountID      string        `json:"account_id,omitempty"`      // Cloudflare account ID
+	AccessKeyID    string        `json:"access_key_id,omitempty"`   // AWS access key ID
+}
+
+// ModelRegion routes a model to a region other than ProviderOptions.Region.
+type ModelRegion struct {
+	Model  string `json:"model"`
+	Region string `json:"region"`
+}
+
 // Master represents an EZ-API master (inferred from New API user).
 type Master struct {
 	Name             string   `json:"name"`                        // Master name (from username)
@@ -64,12 +192,36 @@ type Master struct {
 	MaxChildKeys     int      `json:"max_child_keys,omitempty"`    // Max child keys allowed
 	GlobalQPS        int      `json:"global_qps,omitempty"`        // Global QPS limit
 	Status           string   `json:"status"`                      // active/suspended
+	Role             string   `json:"role,omitempty"`              // guest/user/admin/root
+
+	// Billing. Quotas are in source units; balances are quotas divided by
+	// source.quota_per_unit.
+	Quota        int64   `json:"quota,omitempty"`         // Remaining quota
+	QuotaUsed    int64   `json:"quota_used,omitempty"`    // Used quota
+	Balance      float64 `json:"balance,omitempty"`       // Remaining balance
+	BalanceUsed  float64 `json:"balance_used,omitempty"`  // Used balance
+	RequestCount int64   `json:"request_count,omitempty"` // Requests made
+
+	// Affiliate program
+	AffCode         string `json:"aff_code,omitempty"`          // Invitation code
+	AffCount        int    `json:"aff_count,omitempty"`         // Users invited
+	AffQuota        int64  `json:"aff_quota,omitempty"`         // Unclaimed affiliate reward quota
+	AffHistoryQuota int64  `json:"aff_history_quota,omitempty"` // Affiliate reward quota ever earned
 
 	// Source tracking
-	SourceUserID int    `json:"_source_user_id"` // Original user ID
-	SourceEmail  string `json:"_source_email,omitempty"`
+	SourceUserID  int    `json:"_source_user_id"` // Original user ID
+	SourceEmail   string `json:"_source_email,omitempty"`
+	InviterUserID int    `json:"_inviter_user_id,omitempty"` // Source ID of the inviting user
 }
 
+// User roles.
+const (
+	RoleGuest = "guest"
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	RoleRoot  = "root"
+)
+
 // Key represents an EZ-API key (mapped from New API token).
 type Key struct {
 	MasterRef     string `json:"master_ref"`      // Reference to master name
@@ -109,10 +261,86 @@ type Binding struct {
 	Status     string `json:"status"`      // active/disabled
 }
 
+// FailoverGroup routes requests for a model in a group by priority tier:
+// providers of the first tier are used (weighted) until all of them fail,
+// then the next tier is tried.
+type FailoverGroup struct {
+	Group string         `json:"group"` // Source group (namespace / route group)
+	Model string         `json:"model"` // Model name
+	Tiers []FailoverTier `json:"tiers"` // Highest priority first
+}
+
+// FailoverTier is the set of channels sharing one priority.
+type FailoverTier struct {
+	Priority int64            `json:"priority"` // Source priority
+	Members  []FailoverMember `json:"members"`
+}
+
+// FailoverMember is a channel in a tier. It covers every provider split
+// from the channel.
+type FailoverMember struct {
+	OriginalID int `json:"original_id"`      // Channel ID (provider original_id)
+	Weight     int `json:"weight,omitempty"` // Weight within the tier (0 = equal share)
+}
+
+// Redemption represents a prepaid quota code.
+type Redemption struct {
+	OriginalID int        `json:"original_id"`          // Original redemption ID
+	Name       string     `json:"name,omitempty"`       // Code name
+	Code       string     `json:"code"`                 // Redemption code (plaintext)
+	Quota      int64      `json:"quota"`                // Quota credited when redeemed
+	Status     string     `json:"status"`               // active/disabled/used/expired
+	CreatedAt  *time.Time `json:"created_at,omitempty"` // Creation time
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Expiration time
+
+	// Who created and redeemed the code (source user IDs)
+	CreatorUserID  int        `json:"_creator_user_id,omitempty"`
+	RedeemerUserID int        `json:"_redeemer_user_id,omitempty"`
+	RedeemedAt     *time.Time `json:"redeemed_at,omitempty"`
+}
+
+// Administrator is a source user with admin rights (role admin or root).
+// Administrators who own tokens are also exported as masters, but admin
+// rights are not carried over to their keys.
+type Administrator struct {
+	Name           string     `json:"name"` // Username (master name if exported as master)
+	DisplayName    string     `json:"display_name,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	Role           string     `json:"role"`                       // admin/root
+	Status         string     `json:"status"`                     // active/suspended
+	Identities     []Identity `json:"identities,omitempty"`       // Linked OAuth accounts
+	HasAccessToken bool       `json:"has_access_token,omitempty"` // Has a system access token (not exported)
+
+	// Source tracking
+	SourceUserID int `json:"_source_user_id"` // Original user ID
+}
+
+// Identity is an external account linked to a source user.
+type Identity struct {
+	Provider string `json:"provider"` // e.g. "github", "oidc"
+	Subject  string `json:"subject"`  // Account ID at the provider
+}
+
+// Tombstone kinds.
+const (
+	TombstoneProvider = EntityProvider
+	TombstoneMaster   = EntityMaster
+	TombstoneKey      = EntityKey
+)
+
+// Tombstone records an entity deleted in the source since the previous export.
+// A provider tombstone covers every provider split from the channel.
+type Tombstone struct {
+	Kind       string     `json:"kind"`                 // provider/master/key
+	OriginalID int        `json:"original_id"`          // Channel, user or token ID
+	Name       string     `json:"name,omitempty"`       // Channel or master name, if known
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"` // Soft-delete time, if known
+}
+
 // NewExportResult creates a new export result with default values.
 func NewExportResult() *ExportResult {
 	return &ExportResult{
-		Version: "1.0.0",
+		Version: FormatVersion,
 		Source: Source{
 			Type:       "newapi",
 			Version:    "unknown",
@@ -123,9 +351,10 @@ func NewExportResult() *ExportResult {
 	}
 }
 
-// AddWarning adds a warning message to the export result.
-func (r *ExportResult) AddWarning(msg string) {
-	r.Warnings = append(r.Warnings, msg)
+// AddWarning adds a warning to the export result.
+func (r *ExportResult) AddWarning(w Warning) {
+	r.Warnings = append(r.Warnings, w.Message)
+	r.WarningDetails = append(r.WarningDetails, w)
 }
 
 // AddProvider adds a provider to the export result.
@@ -148,6 +377,26 @@ func (r *ExportResult) AddBinding(b Binding) {
 	r.Data.Bindings = append(r.Data.Bindings, b)
 }
 
+// AddFailoverGroup adds a failover group to the export result.
+func (r *ExportResult) AddFailoverGroup(g FailoverGroup) {
+	r.Data.FailoverGroups = append(r.Data.FailoverGroups, g)
+}
+
+// AddRedemption adds a redemption code to the export result.
+func (r *ExportResult) AddRedemption(c Redemption) {
+	r.Data.Redemptions = append(r.Data.Redemptions, c)
+}
+
+// AddAdministrator adds an administrator to the export result.
+func (r *ExportResult) AddAdministrator(a Administrator) {
+	r.Data.Administrators = append(r.Data.Administrators, a)
+}
+
+// AddTombstone adds a tombstone to the export result.
+func (r *ExportResult) AddTombstone(t Tombstone) {
+	r.Data.Tombstones = append(r.Data.Tombstones, t)
+}
+
 // ToJSON serializes the export result to JSON.
 func (r *ExportResult) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(r, "", "  ")
@@ -155,20 +404,28 @@ func (r *ExportResult) ToJSON() ([]byte, error) {
 
 // Summary returns a summary of exported entities.
 type Summary struct {
-	Providers int `json:"providers"`
-	Masters   int `json:"masters"`
-	Keys      int `json:"keys"`
-	Bindings  int `json:"bindings"`
-	Warnings  int `json:"warnings"`
+	Providers      int `json:"providers"`
+	Masters        int `json:"masters"`
+	Keys           int `json:"keys"`
+	Bindings       int `json:"bindings"`
+	FailoverGroups int `json:"failover_groups"`
+	Redemptions    int `json:"redemptions"`
+	Administrators int `json:"administrators"`
+	Tombstones     int `json:"tombstones"`
+	Warnings       int `json:"warnings"`
 }
 
 // GetSummary returns a summary of the export result.
 func (r *ExportResult) GetSummary() Summary {
 	return Summary{
-		Providers: len(r.Data.Providers),
-		Masters:   len(r.Data.Masters),
-		Keys:      len(r.Data.Keys),
-		Bindings:  len(r.Data.Bindings),
-		Warnings:  len(r.Warnings),
+		Providers:      len(r.Data.Providers),
+		Masters:        len(r.Data.Masters),
+		Keys:           len(r.Data.Keys),
+		Bindings:       len(r.Data.Bindings),
+		FailoverGroups: len(r.Data.FailoverGroups),
+		Redemptions:    len(r.Data.Redemptions),
+		Administrators: len(r.Data.Administrators),
+		Tombstones:     len(r.Data.Tombstones),
+		Warnings:       len(r.Warnings),
 	}
 }
@@ -0,0 +1,319 @@
+// JSON Schema of the intermediate format and a validator for it.
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDialect is the JSON Schema draft the generated schema conforms to.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the subset of JSON Schema used to describe the intermediate format.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"` // string or []string
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// fieldEnums restricts fields (by JSON name) to their allowed values.
+var fieldEnums = map[reflect.Type]map[string][]string{
+	reflect.TypeOf(Source{}):        {"redaction": RedactionModes, "backup": BackupPolicies},
+	reflect.TypeOf(Provider{}):      {"status": ProviderStatuses},
+	reflect.TypeOf(Master{}):        {"status": MasterStatuses, "role": MasterRoles},
+	reflect.TypeOf(Key{}):           {"status": KeyStatuses},
+	reflect.TypeOf(Binding{}):       {"status": BindingStatuses},
+	reflect.TypeOf(Redemption{}):    {"status": RedemptionStatuses},
+	reflect.TypeOf(Administrator{}): {"role": AdministratorRoles, "status": MasterStatuses},
+	reflect.TypeOf(Tombstone{}):     {"kind": TombstoneKinds},
+	reflect.TypeOf(Warning{}):       {"severity": Severities, "kind": EntityKinds},
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// GenerateJSONSchema returns the JSON Schema of ExportResult for the current
+// format version. The "version" field accepts every compatible version, since
+// older files are a subset of the current format. Struct types become $defs
+// entries named after the Go type; fields without omitempty are required,
+// and unknown fields are rejected.
+func GenerateJSONSchema() *JSONSchema {
+	g := &schemaGenerator{defs: make(map[string]*JSONSchema)}
+
+	root := g.structSchema(reflect.TypeOf(ExportResult{}))
+	root.Schema = JSONSchemaDialect
+	root.ID = "urn:ez-api:exporter:intermediate:" + FormatVersion
+	root.Title = "EZ-API exporter intermediate format " + FormatVersion
+	root.Properties["version"].Enum = CompatibleVersions
+	root.Defs = g.defs
+
+	return root
+}
+
+// schemaGenerator builds schemas by reflection, collecting struct definitions.
+type schemaGenerator struct {
+	defs map[string]*JSONSchema
+}
+
+// typeSchema returns the schema of t. nullable allows null, for pointers and
+// slices that are marshaled even when nil.
+func (g *schemaGenerator) typeSchema(t reflect.Type, nullable bool) *JSONSchema {
+	if t.Kind() == reflect.Ptr {
+		return g.typeSchema(t.Elem(), nullable)
+	}
+
+	var s *JSONSchema
+	switch {
+	case t == timeType:
+		s = &JSONSchema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		// Arbitrary JSON
+		return &JSONSchema{}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = g.structSchema(t)
+		}
+		s = &JSONSchema{Ref: "#/$defs/" + name}
+		if nullable {
+			return &JSONSchema{Type: []string{"object", "null"}, Ref: s.Ref}
+		}
+		return s
+	case t.Kind() == reflect.Slice:
+		s = &JSONSchema{Type: "array", Items: g.typeSchema(t.Elem(), false)}
+	case t.Kind() == reflect.String:
+		s = &JSONSchema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		s = &JSONSchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s = &JSONSchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = &JSONSchema{Type: "number"}
+	default:
+		panic(fmt.Sprintf("schema: unsupported type %s", t))
+	}
+
+	if nullable {
+		s.Type = []string{s.Type.(string), "null"}
+	}
+	return s
+}
+
+// structSchema returns an object schema with one property per JSON field.
+func (g *schemaGenerator) structSchema(t reflect.Type) *JSONSchema {
+	closed := false
+	s := &JSONSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema),
+		AdditionalProperties: &closed,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitEmpty, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+
+		kind := field.Type.Kind()
+		nullable := !omitEmpty && (kind == reflect.Ptr || kind == reflect.Slice) && field.Type != rawMessageType
+		prop := g.typeSchema(field.Type, nullable)
+		prop.Enum = fieldEnums[t][name]
+
+		s.Properties[name] = prop
+		if !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+// jsonFieldName returns the JSON name of a struct field and whether it has
+// omitempty. ok is false for fields excluded with `json:"-"`.
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+// ValidateJSON checks a decoded JSON document (as produced by json.Unmarshal
+// into interface{}) against s and returns all violations. Object fields are
+// visited in name order, so the output is deterministic.
+func (s *JSONSchema) ValidateJSON(doc interface{}) []Violation {
+	v := &validator{}
+	s.validateValue(v, s, "", doc)
+	return v.violations
+}
+
+// validateValue checks value at path against schema. s is the root schema
+// holding $defs.
+func (s *JSONSchema) validateValue(v *validator, schema *JSONSchema, path string, value interface{}) {
+	if schema.Ref != "" {
+		if value == nil && schemaAllows(schema.Type, "null") {
+			return
+		}
+		def := s.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+		if def == nil {
+			v.add(displayPath(path), fmt.Sprintf("unresolvable $ref %q", schema.Ref))
+			return
+		}
+		schema = def
+	}
+
+	if schema.Type != nil && !schemaAllows(schema.Type, jsonType(value)) {
+		v.add(displayPath(path), fmt.Sprintf("expected %s, got %s", typeList(schema.Type), jsonType(value)))
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		str, _ := value.(string)
+		if !containsString(schema.Enum, str) {
+			v.add(displayPath(path), fmt.Sprintf("invalid value %q (allowed: %v)", str, schema.Enum))
+		}
+	}
+	if schema.Format == "date-time" {
+		if str, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				v.add(displayPath(path), fmt.Sprintf("invalid date-time %q", str))
+			}
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				v.add(joinPath(path, name), "is required")
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					v.add(joinPath(path, name), "unknown field")
+				}
+				continue
+			}
+			s.validateValue(v, prop, joinPath(path, name), value[name])
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range value {
+				s.validateValue(v, schema.Items, fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		}
+	}
+}
+
+// jsonType returns the JSON Schema type name of a decoded JSON value.
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// schemaAllows reports whether a schema type (string or []string) accepts actual.
+// Integers are also numbers.
+func schemaAllows(schemaType interface{}, actual string) bool {
+	var types []string
+	switch t := schemaType.(type) {
+	case string:
+		types = []string{t}
+	case []string:
+		types = t
+	default:
+		return true
+	}
+
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeList formats a schema type for messages.
+func typeList(schemaType interface{}) string {
+	if types, ok := schemaType.([]string); ok {
+		return strings.Join(types, " or ")
+	}
+	return fmt.Sprint(schemaType)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,219 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fullExport returns a valid export that also sets the optional fields and
+// null values the schema has to accept.
+func fullExport() *ExportResult {
+	r := validExport()
+	snapshot := r.Source.ExportedAt.Add(-time.Second)
+	deleted := r.Source.ExportedAt.Add(-time.Minute)
+
+	r.Source.SnapshotAt = &snapshot
+	r.Source.MissingColumns = []string{"channels.channel_info"}
+	r.Source.Delta = &Delta{Since: r.Source.ExportedAt.Add(-time.Hour)}
+	r.Source.Redaction = RedactMask
+	r.Source.Backup = BackupScrubbed
+	r.Source.QuotaPerUnit = 500000
+
+	p := &r.Data.Providers[0]
+	p.Models = []string{"gpt-4o"}
+	p.ModelAliases = []ModelAlias{{Model: "gpt-4", UpstreamModel: "gpt-4o"}}
+	p.Original = json.RawMessage(`{"id":1,"setting":null,"tags":["a"]}`)
+	p.IsMultiKey, p.MultiKeyIndex, p.DisabledAt = true, 1, &deleted
+
+	r.Data.Masters[0].Balance = 0.5
+	r.Data.FailoverGroups[0].Tiers = nil // Marshaled as null
+	r.Data.Tombstones = []Tombstone{{Kind: TombstoneKey, OriginalID: 11, DeletedAt: &deleted}}
+	r.AddWarning(Warning{
+		Code: WarnUnmappedSetting, Severity: SeverityWarning, Kind: EntityProvider,
+		OriginalID: 1, Field: "setting", Value: WarningValue(map[string]int{"a": 1}), Message: "unmapped setting",
+	})
+	return r
+}
+
+// jsonDoc encodes v and decodes it into a generic JSON document.
+func jsonDoc(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// object returns the object at a dot-separated path of doc, where numeric
+// parts index arrays.
+func object(t *testing.T, doc map[string]interface{}, path string) map[string]interface{} {
+	t.Helper()
+
+	var value interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[part]
+		case []interface{}:
+			var i int
+			if err := json.Unmarshal([]byte(part), &i); err != nil {
+				t.Fatalf("bad index %q in %s", part, path)
+			}
+			value = v[i]
+		}
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s is %T, not an object", path, value)
+	}
+	return obj
+}
+
+func TestValidateJSONExport(t *testing.T) {
+	s := GenerateJSONSchema()
+
+	for name, r := range map[string]*ExportResult{"empty": NewExportResult(), "valid": validExport(), "full": fullExport()} {
+		if violations := s.ValidateJSON(jsonDoc(t, r)); len(violations) != 0 {
+			t.Errorf("%s export has schema violations: %v", name, violations)
+		}
+	}
+
+	// Every compatible version is accepted
+	doc := jsonDoc(t, validExport())
+	for _, version := range CompatibleVersions {
+		doc["version"] = version
+		if violations := s.ValidateJSON(doc); len(violations) != 0 {
+			t.Errorf("version %s: %v", version, violations)
+		}
+	}
+}
+
+func TestValidateJSONViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, doc map[string]interface{})
+		want   []string
+	}{
+		{
+			name: "missing required fields",
+			modify: func(t *testing.T, doc map[string]interface{}) {
+				delete(doc, "version")
+				delete(object(t, doc, "data.keys.0"), "master_ref")
+				delete(object(t, doc, "data.providers.1"), "api_key")
+			},
+			want: []string{
+				"version: is required",
+				"data.keys[0].master_ref: is required",
+				"data.providers[1].api_key: is required",
+			},
+		},
+		{
+			name: "unknown fields",
+			modify: func(t *testing.T, doc map[string]interface{}) {
+				doc["extra"] = true
+				object(t, doc, "data.providers.1.options")["secret_access_key"] = "S3CR3T"
+				object(t, doc, "source")["host"] = "db"
+			},
+			want: []string{
+				"data.providers[1].options.secret_access_key: unknown field",
+				"extra: unknown field",
+				"source.host: unknown field",
+			},
+		},
+		{
+			name: "enum violations",
+			modify: func(t *testing.T, doc map[string]interface{}) {
+				doc["version"] = "2.0.0"
+				object(t, doc, "data.masters.0")["status"] = "banned"
+				object(t, doc, "source")["backup"] = "partial"
+			},
+			want: []string{
+				`data.masters[0].status: invalid value "banned" (allowed: [active suspended])`,
+				`source.backup: invalid value "partial" (allowed: [full scrubbed unmappable none])`,
+				fmt.Sprintf(`version: invalid value "2.0.0" (allowed: %v)`, CompatibleVersions),
+			},
+		},
+		{
+			name: "bad date-time",
+			modify: func(t *testing.T, doc map[string]interface{}) {
+				object(t, doc, "source")["exported_at"] = "2025-01-02 03:04:05"
+				object(t, doc, "data.keys.0")["expires_at"] = "tomorrow"
+			},
+			want: []string{
+				`data.keys[0].expires_at: invalid date-time "tomorrow"`,
+				`source.exported_at: invalid date-time "2025-01-02 03:04:05"`,
+			},
+		},
+		{
+			name: "wrong types",
+			modify: func(t *testing.T, doc map[string]interface{}) {
+				object(t, doc, "data.providers.0")["weight"] = 1.5
+				object(t, doc, "data.providers.0")["models"] = "gpt-4o"
+				object(t, doc, "data.keys.0")["_original_id"] = "10"
+				object(t, doc, "data")["masters"] = nil
+			},
+			want: []string{
+				"data.keys[0]._original_id: expected integer, got string",
+				"data.masters: expected array, got null",
+				"data.providers[0].models: expected array, got string",
+				"data.providers[0].weight: expected integer, got number",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := jsonDoc(t, validExport())
+			tt.modify(t, doc)
+
+			var got []string
+			for _, v := range GenerateJSONSchema().ValidateJSON(doc) {
+				got = append(got, v.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateJSONDeterministic(t *testing.T) {
+	doc := jsonDoc(t, fullExport())
+	for _, path := range []string{"source", "data.providers.1", "data.keys.0"} {
+		obj := object(t, doc, path)
+		obj["zz_unknown"] = 1
+		obj["aa_unknown"] = 1
+		obj["status"] = "unknown"
+	}
+
+	// Fields in name order, array items in document order
+	want := []string{
+		"data.keys[0].aa_unknown: unknown field",
+		`data.keys[0].status: invalid value "unknown" (allowed: [active disabled expired exhausted])`,
+		"data.keys[0].zz_unknown: unknown field",
+		"data.providers[1].aa_unknown: unknown field",
+		`data.providers[1].status: invalid value "unknown" (allowed: [active disabled])`,
+		"data.providers[1].zz_unknown: unknown field",
+		"source.aa_unknown: unknown field",
+		"source.status: unknown field",
+		"source.zz_unknown: unknown field",
+	}
+	for i := 0; i < 20; i++ {
+		var got []string
+		for _, v := range GenerateJSONSchema().ValidateJSON(doc) {
+			got = append(got, v.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: violations\n%s\nwant\n%s", i, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
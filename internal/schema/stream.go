@@ -0,0 +1,275 @@
+// Incremental (streaming) output of the intermediate format.
+
+package schema
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Sink receives exported entities. *ExportResult collects them in memory;
+// *StreamWriter writes them to an io.Writer as they arrive.
+//
+// SetSource must be called before any entity is added. Entities must be
+// added section by section in the order of the Data fields (all providers,
+// then all masters, then keys, bindings, failover groups, redemptions,
+// administrators and tombstones); warnings may be added at any time.
+type Sink interface {
+	SetSource(s Source)
+	AddProvider(p Provider)
+	AddMaster(m Master)
+	AddKey(k Key)
+	AddBinding(b Binding)
+	AddFailoverGroup(g FailoverGroup)
+	AddRedemption(c Redemption)
+	AddAdministrator(a Administrator)
+	AddTombstone(t Tombstone)
+	AddWarning(w Warning)
+}
+
+// SetSource sets the source system information.
+func (r *ExportResult) SetSource(s Source) {
+	r.Source = s
+}
+
+// Data sections in output order. Must match the field order of Data.
+const (
+	sectionProviders = iota
+	sectionMasters
+	sectionKeys
+	sectionBindings
+	sectionFailoverGroups
+	sectionRedemptions
+	sectionAdministrators
+	sectionTombstones
+)
+
+var sectionNames = []string{
+	sectionProviders:      "providers",
+	sectionMasters:        "masters",
+	sectionKeys:           "keys",
+	sectionBindings:       "bindings",
+	sectionFailoverGroups: "failover_groups",
+	sectionRedemptions:    "redemptions",
+	sectionAdministrators: "administrators",
+	sectionTombstones:     "tombstones",
+}
+
+// StreamWriter writes an export incrementally. The output is byte-identical
+// to ExportResult.ToJSON for the same entities, but only warnings are kept
+// in memory, so memory use does not grow with the number of entities.
+//
+// Write errors are sticky: after the first failure all further calls are
+// no-ops and the error is returned by Close.
+type StreamWriter struct {
+	w        *bufio.Writer
+	version  string
+	source   Source
+	warnings []Warning
+	summary  Summary
+
+	started bool // header written
+	section int  // current data section, -1 = none yet
+	count   int  // entities written in current section
+	closed  bool
+	err     error
+}
+
+// NewStreamWriter creates a stream writer with default header values.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	defaults := NewExportResult()
+	return &StreamWriter{
+		w:       bufio.NewWriter(w),
+		version: defaults.Version,
+		source:  defaults.Source,
+		section: -1,
+	}
+}
+
+// SetSource sets the source system information.
+// Calling it after the first entity has been written is an error.
+func (s *StreamWriter) SetSource(src Source) {
+	if s.started && s.err == nil {
+		s.err = fmt.Errorf("source set after export data was written")
+		return
+	}
+	s.source = src
+}
+
+// AddProvider writes a provider.
+func (s *StreamWriter) AddProvider(p Provider) {
+	if s.writeEntity(sectionProviders, p) {
+		s.summary.Providers++
+	}
+}
+
+// AddMaster writes a master.
+func (s *StreamWriter) AddMaster(m Master) {
+	if s.writeEntity(sectionMasters, m) {
+		s.summary.Masters++
+	}
+}
+
+// AddKey writes a key.
+func (s *StreamWriter) AddKey(k Key) {
+	if s.writeEntity(sectionKeys, k) {
+		s.summary.Keys++
+	}
+}
+
+// AddBinding writes a binding.
+func (s *StreamWriter) AddBinding(b Binding) {
+	if s.writeEntity(sectionBindings, b) {
+		s.summary.Bindings++
+	}
+}
+
+// AddFailoverGroup writes a failover group.
+func (s *StreamWriter) AddFailoverGroup(g FailoverGroup) {
+	if s.writeEntity(sectionFailoverGroups, g) {
+		s.summary.FailoverGroups++
+	}
+}
+
+// AddRedemption writes a redemption code.
+func (s *StreamWriter) AddRedemption(c Redemption) {
+	if s.writeEntity(sectionRedemptions, c) {
+		s.summary.Redemptions++
+	}
+}
+
+// AddAdministrator writes an administrator.
+func (s *StreamWriter) AddAdministrator(a Administrator) {
+	if s.writeEntity(sectionAdministrators, a) {
+		s.summary.Administrators++
+	}
+}
+
+// AddTombstone writes a tombstone.
+func (s *StreamWriter) AddTombstone(t Tombstone) {
+	if s.writeEntity(sectionTombstones, t) {
+		s.summary.Tombstones++
+	}
+}
+
+// AddWarning records a warning. Warnings are written by Close.
+func (s *StreamWriter) AddWarning(w Warning) {
+	s.warnings = append(s.warnings, w)
+	s.summary.Warnings++
+}
+
+// Warnings returns the warnings recorded so far.
+func (s *StreamWriter) Warnings() []Warning {
+	return s.warnings
+}
+
+// GetSummary returns a summary of the entities written so far.
+func (s *StreamWriter) GetSummary() Summary {
+	return s.summary
+}
+
+// Close finishes the document and flushes buffered output.
+// It does not close the underlying writer.
+func (s *StreamWriter) Close() error {
+	if s.closed {
+		return s.err
+	}
+	s.closed = true
+
+	s.writeHeader()
+	if s.section >= 0 {
+		s.writeString("\n    ]\n  }")
+	} else {
+		s.writeString("}")
+	}
+
+	if len(s.warnings) > 0 {
+		messages := make([]string, len(s.warnings))
+		for i, w := range s.warnings {
+			messages[i] = w.Message
+		}
+		s.writeString(",\n  \"warnings\": ")
+		s.writeJSON(messages, "  ")
+		s.writeString(",\n  \"warning_details\": ")
+		s.writeJSON(s.warnings, "  ")
+	}
+	s.writeString("\n}")
+
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	return s.err
+}
+
+// writeHeader writes everything up to the opening brace of "data".
+func (s *StreamWriter) writeHeader() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	s.writeString("{\n  \"version\": ")
+	s.writeJSON(s.version, "  ")
+	s.writeString(",\n  \"source\": ")
+	s.writeJSON(s.source, "  ")
+	s.writeString(",\n  \"data\": {")
+}
+
+// writeEntity writes v as the next element of section.
+// Returns false if nothing was written.
+func (s *StreamWriter) writeEntity(section int, v interface{}) bool {
+	if s.err != nil {
+		return false
+	}
+	if s.closed {
+		s.err = fmt.Errorf("write after close")
+		return false
+	}
+	if section < s.section {
+		s.err = fmt.Errorf("%s written after %s", sectionNames[section], sectionNames[s.section])
+		return false
+	}
+
+	s.writeHeader()
+
+	if section > s.section {
+		if s.section >= 0 {
+			s.writeString("\n    ],")
+		}
+		s.writeString("\n    \"" + sectionNames[section] + "\": [")
+		s.section = section
+		s.count = 0
+	}
+
+	if s.count > 0 {
+		s.writeString(",")
+	}
+	s.writeString("\n      ")
+	s.writeJSON(v, "      ")
+	s.count++
+
+	return s.err == nil
+}
+
+// writeJSON writes v indented as it would appear at the given prefix depth.
+func (s *StreamWriter) writeJSON(v interface{}, prefix string) {
+	if s.err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(v, prefix, "  ")
+	if err != nil {
+		s.err = err
+		return
+	}
+	_, s.err = s.w.Write(data)
+}
+
+// writeString writes raw output.
+func (s *StreamWriter) writeString(str string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.WriteString(str)
+}
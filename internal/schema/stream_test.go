@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fill adds n entities of every section and a warning to sink.
+func fill(sink Sink, n int) {
+	sink.SetSource(Source{Type: "test", ExportedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)})
+	for i := 0; i < n; i++ {
+		sink.AddProvider(Provider{})
+	}
+	for i := 0; i < n+1; i++ {
+		sink.AddMaster(Master{})
+	}
+	for i := 0; i < n+2; i++ {
+		sink.AddKey(Key{})
+	}
+	for i := 0; i < n+3; i++ {
+		sink.AddBinding(Binding{})
+	}
+	for i := 0; i < n+4; i++ {
+		sink.AddFailoverGroup(FailoverGroup{})
+	}
+	for i := 0; i < n+5; i++ {
+		sink.AddRedemption(Redemption{})
+	}
+	for i := 0; i < n+6; i++ {
+		sink.AddAdministrator(Administrator{})
+	}
+	for i := 0; i < n+7; i++ {
+		sink.AddTombstone(Tombstone{})
+	}
+	sink.AddWarning(Warning{Code: WarnUnknownChannelType, Message: "test"})
+}
+
+func TestStreamWriterMatchesExportResult(t *testing.T) {
+	result := NewExportResult()
+	fill(result, 1)
+	want, err := result.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	stream := NewStreamWriter(&buf)
+	fill(stream, 1)
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("stream output differs from ToJSON:\n%s\nwant:\n%s", buf.Bytes(), want)
+	}
+	if got, want := stream.GetSummary(), result.GetSummary(); got != want {
+		t.Errorf("stream summary %+v, want %+v", got, want)
+	}
+	counts := Summary{
+		Providers: 1, Masters: 2, Keys: 3, Bindings: 4, FailoverGroups: 5,
+		Redemptions: 6, Administrators: 7, Tombstones: 8, Warnings: 1,
+	}
+	if got := result.GetSummary(); got != counts {
+		t.Errorf("summary %+v, want %+v", got, counts)
+	}
+}
@@ -0,0 +1,232 @@
+// Strict decoding and consistency checks of export files.
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Allowed status values per entity, and tombstone kinds.
+var (
+	ProviderStatuses   = []string{"active", "disabled"}
+	MasterStatuses     = []string{"active", "suspended"}
+	KeyStatuses        = []string{"active", "disabled", "expired", "exhausted"}
+	BindingStatuses    = []string{"active", "disabled"}
+	RedemptionStatuses = []string{"active", "disabled", "used", "expired"}
+	TombstoneKinds     = []string{TombstoneProvider, TombstoneMaster, TombstoneKey}
+)
+
+// CredentialTypes lists the allowed provider credential types.
+var CredentialTypes = []string{CredentialAPIKey, CredentialAccessKey, CredentialServiceAccount}
+
+// Allowed roles of masters and administrators.
+var (
+	MasterRoles        = []string{RoleGuest, RoleUser, RoleAdmin, RoleRoot}
+	AdministratorRoles = []string{RoleAdmin, RoleRoot}
+)
+
+// Violation is a single problem found in an export file.
+type Violation struct {
+	Path    string // JSON path of the offending value, e.g. "data.keys[3].master_ref"
+	Message string // Human-readable description
+}
+
+// String returns the violation as "path: message".
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Decode strictly decodes an export file. Unknown fields and trailing data
+// are rejected.
+func Decode(data []byte) (*ExportResult, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var result ExportResult
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return &result, nil
+}
+
+// Validate checks the required fields and cross-references of an export and
+// returns all violations found, in document order.
+func (r *ExportResult) Validate() []Violation {
+	v := &validator{}
+
+	if r.Version == "" {
+		v.add("version", "is required")
+	}
+	if r.Source.Type == "" {
+		v.add("source.type", "is required")
+	}
+	if r.Source.ExportedAt.IsZero() {
+		v.add("source.exported_at", "is required")
+	}
+
+	providerNames := make(map[string]int)
+	for i, p := range r.Data.Providers {
+		path := fmt.Sprintf("data.providers[%d]", i)
+		if p.Name == "" {
+			v.add(path+".name", "is required")
+		} else if first, ok := providerNames[p.Name]; ok {
+			v.add(path+".name", fmt.Sprintf("duplicate provider name %q (first used at data.providers[%d])", p.Name, first))
+		} else {
+			providerNames[p.Name] = i
+		}
+		v.checkEnum(path+".status", p.Status, ProviderStatuses)
+		v.checkTime(path+".disabled_at", p.DisabledAt)
+		if p.Options != nil && p.Options.CredentialType != "" {
+			v.checkEnum(path+".options.credential_type", p.Options.CredentialType, CredentialTypes)
+		}
+	}
+
+	masterNames := make(map[string]bool)
+	namespaces := make(map[string]bool)
+	for i, m := range r.Data.Masters {
+		path := fmt.Sprintf("data.masters[%d]", i)
+		if m.Name == "" {
+			v.add(path+".name", "is required")
+		} else if masterNames[m.Name] {
+			v.add(path+".name", fmt.Sprintf("duplicate master name %q", m.Name))
+		}
+		masterNames[m.Name] = true
+		v.checkEnum(path+".status", m.Status, MasterStatuses)
+		if m.Role != "" {
+			v.checkEnum(path+".role", m.Role, MasterRoles)
+		}
+
+		for _, ns := range m.Namespaces {
+			namespaces[ns] = true
+		}
+		if m.DefaultNamespace != "" {
+			namespaces[m.DefaultNamespace] = true
+		}
+	}
+
+	for i, k := range r.Data.Keys {
+		path := fmt.Sprintf("data.keys[%d]", i)
+		if k.MasterRef == "" {
+			v.add(path+".master_ref", "is required")
+		} else if !masterNames[k.MasterRef] {
+			v.add(path+".master_ref", fmt.Sprintf("references unknown master %q", k.MasterRef))
+		}
+		v.checkEnum(path+".status", k.Status, KeyStatuses)
+		v.checkTime(path+".expires_at", k.ExpiresAt)
+
+		for _, ns := range k.Namespaces {
+			namespaces[ns] = true
+		}
+	}
+
+	// Bindings can only be checked against namespaces when masters or keys
+	// were exported (they are skipped with --include-tokens=false).
+	checkNamespaces := len(r.Data.Masters) > 0 || len(r.Data.Keys) > 0
+	for i, b := range r.Data.Bindings {
+		path := fmt.Sprintf("data.bindings[%d]", i)
+		if b.Namespace == "" {
+			v.add(path+".namespace", "is required")
+		} else if checkNamespaces && !namespaces[b.Namespace] {
+			v.add(path+".namespace", fmt.Sprintf("namespace %q is not used by any master or key", b.Namespace))
+		}
+		v.checkEnum(path+".status", b.Status, BindingStatuses)
+	}
+
+	// Failover members can only be checked against providers in full exports
+	// that include providers; incremental exports leave unchanged channels out.
+	channelIDs := make(map[int]bool)
+	for _, p := range r.Data.Providers {
+		channelIDs[p.OriginalID] = true
+	}
+	checkChannels := r.Source.Delta == nil && len(r.Data.Providers) > 0
+	for i, g := range r.Data.FailoverGroups {
+		path := fmt.Sprintf("data.failover_groups[%d]", i)
+		if g.Group == "" {
+			v.add(path+".group", "is required")
+		}
+		if g.Model == "" {
+			v.add(path+".model", "is required")
+		}
+		if len(g.Tiers) == 0 {
+			v.add(path+".tiers", "must not be empty")
+		}
+		for j, t := range g.Tiers {
+			tierPath := fmt.Sprintf("%s.tiers[%d]", path, j)
+			if j > 0 && t.Priority >= g.Tiers[j-1].Priority {
+				v.add(tierPath+".priority", fmt.Sprintf("tiers must be in descending priority order (%d after %d)", t.Priority, g.Tiers[j-1].Priority))
+			}
+			if len(t.Members) == 0 {
+				v.add(tierPath+".members", "must not be empty")
+			}
+			for k, m := range t.Members {
+				if checkChannels && !channelIDs[m.OriginalID] {
+					v.add(fmt.Sprintf("%s.members[%d].original_id", tierPath, k), fmt.Sprintf("references unknown provider original_id %d", m.OriginalID))
+				}
+			}
+		}
+	}
+
+	for i, c := range r.Data.Redemptions {
+		path := fmt.Sprintf("data.redemptions[%d]", i)
+		if c.Code == "" {
+			v.add(path+".code", "is required")
+		}
+		v.checkEnum(path+".status", c.Status, RedemptionStatuses)
+		v.checkTime(path+".expires_at", c.ExpiresAt)
+	}
+
+	adminNames := make(map[string]bool)
+	for i, a := range r.Data.Administrators {
+		path := fmt.Sprintf("data.administrators[%d]", i)
+		if a.Name == "" {
+			v.add(path+".name", "is required")
+		} else if adminNames[a.Name] {
+			v.add(path+".name", fmt.Sprintf("duplicate administrator name %q", a.Name))
+		}
+		adminNames[a.Name] = true
+		v.checkEnum(path+".role", a.Role, AdministratorRoles)
+		v.checkEnum(path+".status", a.Status, MasterStatuses)
+	}
+
+	for i, t := range r.Data.Tombstones {
+		path := fmt.Sprintf("data.tombstones[%d]", i)
+		v.checkEnum(path+".kind", t.Kind, TombstoneKinds)
+		v.checkTime(path+".deleted_at", t.DeletedAt)
+	}
+
+	return v.violations
+}
+
+// validator accumulates violations.
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(path, msg string) {
+	v.violations = append(v.violations, Violation{Path: path, Message: msg})
+}
+
+// checkEnum reports value if it is not one of allowed.
+func (v *validator) checkEnum(path, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(path, fmt.Sprintf("invalid value %q (allowed: %v)", value, allowed))
+}
+
+// checkTime reports timestamps at or before the Unix epoch, which come from
+// zero or negative source timestamps that should have been omitted.
+func (v *validator) checkTime(path string, t *time.Time) {
+	if t != nil && !t.After(time.Unix(0, 0)) {
+		v.add(path, fmt.Sprintf("timestamp %s is not after the Unix epoch", t.Format(time.RFC3339)))
+	}
+}
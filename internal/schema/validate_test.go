@@ -0,0 +1,223 @@
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validExport returns an export with every section filled in that passes
+// Validate.
+func validExport() *ExportResult {
+	exportedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := exportedAt.Add(24 * time.Hour)
+
+	r := NewExportResult()
+	r.Source = Source{Type: "newapi", Version: "unknown", ExportedAt: exportedAt}
+	r.Data = Data{
+		Providers: []Provider{
+			{OriginalID: 1, Name: "openai", Type: "openai", APIKey: "sk-1", PrimaryGroup: "default", Weight: 1, Status: "active"},
+			{OriginalID: 2, Name: "bedrock", Type: "aws", APIKey: "S3CR3T", PrimaryGroup: "default", Weight: 1, Status: "disabled",
+				Options: &ProviderOptions{CredentialType: CredentialAccessKey, AccessKeyID: "AKID", Region: "us-east-1"}},
+		},
+		Masters: []Master{
+			{Name: "alice", Group: "default", Namespaces: []string{"default"}, DefaultNamespace: "default", Status: "active", Role: RoleUser, SourceUserID: 1},
+		},
+		Keys: []Key{
+			{MasterRef: "alice", OriginalToken: "tok", Status: "active", ExpiresAt: &expires, OriginalID: 10},
+		},
+		Bindings: []Binding{
+			{Namespace: "default", RouteGroup: "default", Model: "gpt-4o", Status: "active"},
+		},
+		FailoverGroups: []FailoverGroup{
+			{Group: "default", Model: "gpt-4o", Tiers: []FailoverTier{
+				{Priority: 10, Members: []FailoverMember{{OriginalID: 1}}},
+				{Priority: 0, Members: []FailoverMember{{OriginalID: 2, Weight: 1}}},
+			}},
+		},
+		Redemptions: []Redemption{
+			{OriginalID: 1, Code: "code", Quota: 1000, Status: "used", CreatedAt: &exportedAt},
+		},
+		Administrators: []Administrator{
+			{Name: "root", Role: RoleRoot, Status: "active", SourceUserID: 2},
+		},
+	}
+	return r
+}
+
+func TestValidateValidExport(t *testing.T) {
+	if violations := validExport().Validate(); len(violations) != 0 {
+		t.Errorf("valid export has violations: %v", violations)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	epoch := time.Unix(0, 0)
+
+	tests := []struct {
+		name   string
+		modify func(r *ExportResult)
+		want   []string
+	}{
+		{
+			name: "missing header",
+			modify: func(r *ExportResult) {
+				r.Version = ""
+				r.Source.Type = ""
+				r.Source.ExportedAt = time.Time{}
+			},
+			want: []string{"version: is required", "source.type: is required", "source.exported_at: is required"},
+		},
+		{
+			name:   "unknown master reference",
+			modify: func(r *ExportResult) { r.Data.Keys[0].MasterRef = "bob" },
+			want:   []string{`data.keys[0].master_ref: references unknown master "bob"`},
+		},
+		{
+			name:   "missing master reference",
+			modify: func(r *ExportResult) { r.Data.Keys[0].MasterRef = "" },
+			want:   []string{"data.keys[0].master_ref: is required"},
+		},
+		{
+			name:   "unknown failover provider",
+			modify: func(r *ExportResult) { r.Data.FailoverGroups[0].Tiers[1].Members[0].OriginalID = 9 },
+			want:   []string{"data.failover_groups[0].tiers[1].members[0].original_id: references unknown provider original_id 9"},
+		},
+		{
+			// Incremental exports leave unchanged channels out
+			name: "unknown failover provider in delta",
+			modify: func(r *ExportResult) {
+				r.Source.Delta = &Delta{Since: r.Source.ExportedAt.Add(-time.Hour)}
+				r.Data.FailoverGroups[0].Tiers[1].Members[0].OriginalID = 9
+			},
+		},
+		{
+			name:   "unused binding namespace",
+			modify: func(r *ExportResult) { r.Data.Bindings[0].Namespace = "vip" },
+			want:   []string{`data.bindings[0].namespace: namespace "vip" is not used by any master or key`},
+		},
+		{
+			// Masters and keys are skipped with --include-tokens=false
+			name: "binding namespace without masters and keys",
+			modify: func(r *ExportResult) {
+				r.Data.Masters, r.Data.Keys = nil, nil
+				r.Data.Bindings[0].Namespace = "vip"
+			},
+		},
+		{
+			name: "duplicate names",
+			modify: func(r *ExportResult) {
+				r.Data.Providers[1].Name = "openai"
+				r.Data.Masters = append(r.Data.Masters, Master{Name: "alice", Status: "active", SourceUserID: 3})
+				r.Data.Administrators = append(r.Data.Administrators, Administrator{Name: "root", Role: RoleAdmin, Status: "active"})
+			},
+			want: []string{
+				`data.providers[1].name: duplicate provider name "openai" (first used at data.providers[0])`,
+				`data.masters[1].name: duplicate master name "alice"`,
+				`data.administrators[1].name: duplicate administrator name "root"`,
+			},
+		},
+		{
+			name: "enum violations",
+			modify: func(r *ExportResult) {
+				r.Data.Providers[0].Status = "enabled"
+				r.Data.Providers[1].Options.CredentialType = "password"
+				r.Data.Masters[0].Status = "disabled"
+				r.Data.Masters[0].Role = "owner"
+				r.Data.Keys[0].Status = ""
+				r.Data.Bindings[0].Status = "used"
+				r.Data.Redemptions[0].Status = "exhausted"
+				r.Data.Administrators[0].Role = RoleUser
+				r.Data.Tombstones = []Tombstone{{Kind: "binding", OriginalID: 1}}
+			},
+			want: []string{
+				`data.providers[0].status: invalid value "enabled" (allowed: [active disabled])`,
+				`data.providers[1].options.credential_type: invalid value "password" (allowed: [api_key access_key service_account])`,
+				`data.masters[0].status: invalid value "disabled" (allowed: [active suspended])`,
+				`data.masters[0].role: invalid value "owner" (allowed: [guest user admin root])`,
+				`data.keys[0].status: invalid value "" (allowed: [active disabled expired exhausted])`,
+				`data.bindings[0].status: invalid value "used" (allowed: [active disabled])`,
+				`data.redemptions[0].status: invalid value "exhausted" (allowed: [active disabled used expired])`,
+				`data.administrators[0].role: invalid value "user" (allowed: [admin root])`,
+				`data.tombstones[0].kind: invalid value "binding" (allowed: [provider master key])`,
+			},
+		},
+		{
+			name: "failover tiers",
+			modify: func(r *ExportResult) {
+				r.Data.FailoverGroups = append(r.Data.FailoverGroups,
+					FailoverGroup{Tiers: []FailoverTier{{Priority: 0, Members: []FailoverMember{{OriginalID: 1}}}, {Priority: 5}}},
+					FailoverGroup{Group: "default", Model: "gpt-4o-mini"},
+				)
+			},
+			want: []string{
+				"data.failover_groups[1].group: is required",
+				"data.failover_groups[1].model: is required",
+				"data.failover_groups[1].tiers[1].priority: tiers must be in descending priority order (5 after 0)",
+				"data.failover_groups[1].tiers[1].members: must not be empty",
+				"data.failover_groups[2].tiers: must not be empty",
+			},
+		},
+		{
+			name: "timestamps at the Unix epoch",
+			modify: func(r *ExportResult) {
+				r.Data.Keys[0].ExpiresAt = &epoch
+				r.Data.Tombstones = []Tombstone{{Kind: TombstoneKey, OriginalID: 11, DeletedAt: &epoch}}
+			},
+			want: []string{
+				"data.keys[0].expires_at: timestamp 1970-01-01T00:00:00Z is not after the Unix epoch",
+				"data.tombstones[0].deleted_at: timestamp 1970-01-01T00:00:00Z is not after the Unix epoch",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validExport()
+			tt.modify(r)
+
+			var got []string
+			for _, v := range r.Validate() {
+				got = append(got, v.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	data, err := validExport().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := result.ToJSON(); err != nil || !bytes.Equal(again, data) {
+		t.Errorf("decoded export encodes as\n%s\nwant\n%s", again, data)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "unknown top-level field", data: `{"version":"1.10.0","extra":1}`, err: `unknown field "extra"`},
+		{name: "unknown nested field", data: `{"data":{"providers":[{"name":"a","api_keys":[]}]}}`, err: `unknown field "api_keys"`},
+		{name: "trailing data", data: `{"version":"1.10.0"} {}`, err: "unexpected data after top-level value"},
+		{name: "wrong type", data: `{"data":{"keys":[{"_original_id":"10"}]}}`, err: "cannot unmarshal string"},
+		{name: "truncated", data: `{"version":`, err: "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Structured export warnings.
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Warning severities, in increasing order.
+const (
+	SeverityInfo    = "info"    // Informational; the migrated entity behaves the same
+	SeverityWarning = "warning" // Behavior may differ; data is kept only in _original
+	SeverityError   = "error"   // Data was dropped or could not be migrated
+)
+
+// Severities lists the warning severities in increasing order.
+var Severities = []string{SeverityInfo, SeverityWarning, SeverityError}
+
+// Entity kinds referenced by warnings and tombstones.
+const (
+	EntityProvider   = "provider"
+	EntityMaster     = "master"
+	EntityKey        = "key"
+	EntityBinding    = "binding"
+	EntityRedemption = "redemption"
+)
+
+// EntityKinds lists the entity kinds referenced by warnings.
+var EntityKinds = []string{EntityProvider, EntityMaster, EntityKey, EntityBinding, EntityRedemption}
+
+// Warning codes. Codes are stable and can be used to filter or suppress
+// warnings; messages may change.
+const (
+	WarnUnknownChannelType       = "UNKNOWN_CHANNEL_TYPE"
+	WarnDuplicateChannelName     = "DUPLICATE_CHANNEL_NAME"
+	WarnUnsupportedPriority      = "UNSUPPORTED_PRIORITY"
+	WarnMultipleGroups           = "MULTIPLE_GROUPS"
+	WarnUnmappedStatusCodeMap    = "UNMAPPED_STATUS_CODE_MAPPING"
+	WarnUnmappedSetting          = "UNMAPPED_SETTING"
+	WarnUnmappedParamOverride    = "UNMAPPED_PARAM_OVERRIDE"
+	WarnUnmappedHeaderOverride   = "UNMAPPED_HEADER_OVERRIDE"
+	WarnUnmappedConfig           = "UNMAPPED_CONFIG"
+	WarnUnmappedSystemPrompt     = "UNMAPPED_SYSTEM_PROMPT"
+	WarnInvalidModelMapping      = "INVALID_MODEL_MAPPING"
+	WarnInvalidModelMappingEntry = "INVALID_MODEL_MAPPING_ENTRY"
+	WarnModelMappingCycle        = "MODEL_MAPPING_CYCLE"
+	WarnInvalidCredentials       = "INVALID_CREDENTIALS"
+	WarnOrphanedTokens           = "ORPHANED_TOKENS"
+	WarnRedemptionUsed           = "REDEMPTION_USED"
+	WarnRedemptionExpired        = "REDEMPTION_EXPIRED"
+	WarnAdminTokenScopes         = "ADMIN_TOKEN_SCOPES"
+	WarnMissingTable             = "MISSING_TABLE"
+)
+
+// WarningCodes lists all warning codes.
+var WarningCodes = []string{
+	WarnUnknownChannelType,
+	WarnDuplicateChannelName,
+	WarnUnsupportedPriority,
+	WarnMultipleGroups,
+	WarnUnmappedStatusCodeMap,
+	WarnUnmappedSetting,
+	WarnUnmappedParamOverride,
+	WarnUnmappedHeaderOverride,
+	WarnUnmappedConfig,
+	WarnUnmappedSystemPrompt,
+	WarnInvalidModelMapping,
+	WarnInvalidModelMappingEntry,
+	WarnModelMappingCycle,
+	WarnInvalidCredentials,
+	WarnOrphanedTokens,
+	WarnRedemptionUsed,
+	WarnRedemptionExpired,
+	WarnAdminTokenScopes,
+	WarnMissingTable,
+}
+
+// Warning is a problem found while mapping source data.
+type Warning struct {
+	Code       string          `json:"code"`                  // Stable warning code, e.g. "UNMAPPED_SETTING"
+	Severity   string          `json:"severity"`              // info/warning/error
+	Kind       string          `json:"kind,omitempty"`        // Kind of the affected entity
+	OriginalID int             `json:"original_id,omitempty"` // Source ID of the affected entity
+	Name       string          `json:"name,omitempty"`        // Source name of the affected entity
+	Field      string          `json:"field,omitempty"`       // Source field, e.g. "setting"
+	Value      json.RawMessage `json:"value,omitempty"`       // Original value of Field
+	Message    string          `json:"message"`               // Human-readable description
+}
+
+// String returns the warning message.
+func (w Warning) String() string {
+	return w.Message
+}
+
+// WarningValue encodes a source value for Warning.Value. nil and values that
+// cannot be encoded are left out.
+func WarningValue(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// SeverityRank returns the position of severity in Severities, or -1.
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// ValidSeverity checks that severity is one of Severities.
+func ValidSeverity(severity string) error {
+	if SeverityRank(severity) < 0 {
+		return fmt.Errorf("unsupported severity: %s (available: %v)", severity, Severities)
+	}
+	return nil
+}
+
+// ValidWarningCode checks that code is one of WarningCodes.
+func ValidWarningCode(code string) error {
+	for _, c := range WarningCodes {
+		if c == code {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown warning code: %s (available: %v)", code, WarningCodes)
+}
+
+// CountAtLeast returns the number of warnings with at least the given severity.
+func CountAtLeast(warnings []Warning, severity string) int {
+	rank := SeverityRank(severity)
+	n := 0
+	for _, w := range warnings {
+		if SeverityRank(w.Severity) >= rank {
+			n++
+		}
+	}
+	return n
+}
+
+// SuppressWarnings returns a sink that drops warnings with the given codes
+// before passing the rest on to sink.
+func SuppressWarnings(sink Sink, codes []string) Sink {
+	if len(codes) == 0 {
+		return sink
+	}
+	suppressed := make(map[string]bool, len(codes))
+	for _, c := range codes {
+		suppressed[c] = true
+	}
+	return &suppressSink{Sink: sink, codes: suppressed}
+}
+
+// suppressSink drops suppressed warnings.
+type suppressSink struct {
+	Sink
+	codes map[string]bool
+}
+
+// AddWarning passes w on unless its code is suppressed.
+func (s *suppressSink) AddWarning(w Warning) {
+	if !s.codes[w.Code] {
+		s.Sink.AddWarning(w)
+	}
+}
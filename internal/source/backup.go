@@ -0,0 +1,76 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// DefaultBackup is the _original backup policy used when none is given.
+// It keeps the complete row, as exports did before backup policies.
+const DefaultBackup = schema.BackupFull
+
+// ValidBackup checks that policy is one of schema.BackupPolicies.
+func ValidBackup(policy string) error {
+	for _, p := range schema.BackupPolicies {
+		if p == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported backup policy: %s (available: %v)", policy, schema.BackupPolicies)
+}
+
+// Backup builds the _original backup of a source row according to policy.
+// secretFields are the JSON fields holding key material, removed by every
+// policy but full. unmappable are the JSON fields kept by the unmappable
+// policy; fields that are null or empty are left out. Returns nil when
+// nothing is left to back up.
+func Backup(policy string, row interface{}, secretFields, unmappable []string) json.RawMessage {
+	if policy == schema.BackupNone {
+		return nil
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil || policy == schema.BackupFull {
+		return data
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+
+	for _, name := range secretFields {
+		delete(fields, name)
+	}
+
+	if policy == schema.BackupUnmappable {
+		kept := make(map[string]json.RawMessage, len(unmappable))
+		for _, name := range unmappable {
+			if value, ok := fields[name]; ok && !emptyJSON(value) {
+				kept[name] = value
+			}
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		fields = kept
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// emptyJSON reports whether value is null, an empty string or an empty
+// object (as a value or JSON-encoded in a string).
+func emptyJSON(value json.RawMessage) bool {
+	switch string(value) {
+	case "null", `""`, "{}", `"{}"`:
+		return true
+	}
+	return false
+}
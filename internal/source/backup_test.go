@@ -0,0 +1,87 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+func TestBackup(t *testing.T) {
+	type row struct {
+		ID      int               `json:"id"`
+		Key     string            `json:"key"`
+		Setting *string           `json:"setting"`
+		Headers string            `json:"header_override"`
+		Params  map[string]string `json:"param_override"`
+		Tag     string            `json:"tag"`
+	}
+	setting := `{"proxy":"http://proxy"}`
+	full := row{ID: 1, Key: "sk-1\nsk-2", Setting: &setting, Headers: "{}", Tag: "prod"}
+	empty := row{ID: 2, Key: "sk-3", Params: map[string]string{}}
+
+	secretFields := []string{"key"}
+	unmappable := []string{"setting", "header_override", "param_override"}
+
+	tests := []struct {
+		name   string
+		policy string
+		row    row
+		want   string // Backup JSON, "" for none
+	}{
+		{
+			name:   "full keeps everything",
+			policy: schema.BackupFull,
+			row:    full,
+			want:   `{"id":1,"key":"sk-1\nsk-2","setting":"{\"proxy\":\"http://proxy\"}","header_override":"{}","param_override":null,"tag":"prod"}`,
+		},
+		{
+			name:   "scrubbed drops key material",
+			policy: schema.BackupScrubbed,
+			row:    full,
+			want:   `{"header_override":"{}","id":1,"param_override":null,"setting":"{\"proxy\":\"http://proxy\"}","tag":"prod"}`,
+		},
+		{
+			name:   "unmappable keeps non-empty unmappable fields",
+			policy: schema.BackupUnmappable,
+			row:    full,
+			want:   `{"setting":"{\"proxy\":\"http://proxy\"}"}`,
+		},
+		{
+			name:   "unmappable with nothing to keep",
+			policy: schema.BackupUnmappable,
+			row:    empty,
+		},
+		{
+			name:   "none",
+			policy: schema.BackupNone,
+			row:    full,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Backup(tt.policy, tt.row, secretFields, unmappable)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("backup %s, want none", got)
+				}
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("backup\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidBackup(t *testing.T) {
+	for _, policy := range schema.BackupPolicies {
+		if err := ValidBackup(policy); err != nil {
+			t.Errorf("ValidBackup(%q): %v", policy, err)
+		}
+	}
+	for _, policy := range []string{"", "Full", "partial"} {
+		if err := ValidBackup(policy); err == nil {
+			t.Errorf("ValidBackup(%q) accepted", policy)
+		}
+	}
+}
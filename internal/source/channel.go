@@ -0,0 +1,202 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// Channel identifies a source channel in warnings.
+type Channel struct {
+	ID   int
+	Name string
+}
+
+// Warn records a warning about a field of the channel, with the field's
+// original value.
+func (ch Channel) Warn(sink schema.Sink, code, severity, field string, value interface{}, msg string) {
+	sink.AddWarning(schema.Warning{
+		Code:       code,
+		Severity:   severity,
+		Kind:       schema.EntityProvider,
+		OriginalID: ch.ID,
+		Name:       ch.Name,
+		Field:      field,
+		Value:      schema.WarningValue(value),
+		Message:    msg,
+	})
+}
+
+// ParseGroups parses a comma-separated group list. Channels without a
+// group are in the "default" group.
+func ParseGroups(group string) []string {
+	var groups []string
+	for _, part := range strings.Split(group, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			groups = append(groups, part)
+		}
+	}
+
+	if len(groups) == 0 {
+		return []string{"default"}
+	}
+
+	return groups
+}
+
+// ParseModels parses a comma-separated model list.
+func ParseModels(models string) []string {
+	var result []string
+	for _, part := range strings.Split(models, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// ChannelWeight returns the provider weight and priority of a channel.
+// Unset and non-positive weights are 1, unset and negative priorities 0.
+// Priority is not a weight: tiers are exported as failover groups.
+func ChannelWeight(weight *uint, priority *int64) (int, int) {
+	w, p := 1, 0
+	if weight != nil && *weight > 0 {
+		w = int(*weight)
+	}
+	if priority != nil && *priority > 0 {
+		p = int(*priority)
+	}
+	return w, p
+}
+
+// CheckRouting warns about the routing settings of a channel that a single
+// provider cannot express: a priority, unless failover groups are exported
+// with the abilities, and groups beyond the primary one.
+func CheckRouting(sink schema.Sink, ch Channel, group string, priority *int64, includeAbilities bool) {
+	if priority != nil && *priority != 0 && !includeAbilities {
+		ch.Warn(sink, schema.WarnUnsupportedPriority, schema.SeverityInfo, "priority", *priority, fmt.Sprintf(
+			"Channel '%s' (ID=%d) has priority=%d; export with --include-abilities to keep priority tiers as failover groups",
+			ch.Name, ch.ID, *priority,
+		))
+	}
+
+	groups := ParseGroups(group)
+	if len(groups) > 1 {
+		ch.Warn(sink, schema.WarnMultipleGroups, schema.SeverityInfo, "group", group, fmt.Sprintf(
+			"Channel '%s' (ID=%d) belongs to multiple groups %v. Only '%s' is used as primary group. Consider creating Bindings for other groups.",
+			ch.Name, ch.ID, groups, groups[0],
+		))
+	}
+}
+
+// ChannelNames counts the channels using each name.
+type ChannelNames map[string]int
+
+// ProviderName returns the provider name of a channel split into keys
+// providers, named "<name>", "<name>-2", ... EZ-API provider names are
+// unique, so a channel whose name, or the name of one of its split
+// providers, is used by another channel gets a "-<id>" suffix, with a
+// warning.
+func (n ChannelNames) ProviderName(sink schema.Sink, ch Channel, keys int) string {
+	shared := n[ch.Name] > 1
+	for i := 2; i <= keys && !shared; i++ {
+		shared = n[fmt.Sprintf("%s-%d", ch.Name, i)] > 0
+	}
+	if !shared {
+		return ch.Name
+	}
+
+	name := fmt.Sprintf("%s-%d", ch.Name, ch.ID)
+	ch.Warn(sink, schema.WarnDuplicateChannelName, schema.SeverityWarning, "name", ch.Name, fmt.Sprintf(
+		"Channel '%s' (ID=%d) shares its name with other channels, exported as '%s'",
+		ch.Name, ch.ID, name,
+	))
+	return name
+}
+
+// ModelAliases translates a channel's model_mapping JSON into provider
+// model aliases. With chained, mappings (a -> b, b -> c) are resolved to
+// their final target the way New API does at request time; otherwise each
+// entry is applied once, as in One API. Entries that cannot be expressed
+// are skipped with a warning. mapping may be nil.
+func ModelAliases(sink schema.Sink, ch Channel, mapping *string, chained bool) []schema.ModelAlias {
+	if mapping == nil || strings.TrimSpace(*mapping) == "" || strings.TrimSpace(*mapping) == "{}" {
+		return nil
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(*mapping), &raw); err != nil {
+		ch.Warn(sink, schema.WarnInvalidModelMapping, schema.SeverityError, "model_mapping", *mapping, fmt.Sprintf(
+			"Channel '%s' (ID=%d) has invalid model_mapping JSON, not migrated: %v",
+			ch.Name, ch.ID, err,
+		))
+		return nil
+	}
+
+	publicModels := make([]string, 0, len(raw))
+	for from := range raw {
+		publicModels = append(publicModels, from)
+	}
+	sort.Strings(publicModels)
+
+	targets := make(map[string]string, len(raw))
+	for _, from := range publicModels {
+		to := raw[from]
+		target, ok := to.(string)
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(target) == "" {
+			ch.Warn(sink, schema.WarnInvalidModelMappingEntry, schema.SeverityError, "model_mapping", map[string]interface{}{from: to}, fmt.Sprintf(
+				"Channel '%s' (ID=%d) model_mapping entry %q -> %v is not a model name, skipped",
+				ch.Name, ch.ID, from, to,
+			))
+			continue
+		}
+		targets[from] = target
+	}
+
+	var aliases []schema.ModelAlias
+	for _, from := range publicModels {
+		target, ok := targets[from]
+		if !ok {
+			continue
+		}
+		if chained {
+			if target, ok = resolveModelMapping(targets, from); !ok {
+				ch.Warn(sink, schema.WarnModelMappingCycle, schema.SeverityError, "model_mapping", from, fmt.Sprintf(
+					"Channel '%s' (ID=%d) model_mapping for %q contains a cycle, skipped",
+					ch.Name, ch.ID, from,
+				))
+				continue
+			}
+		}
+		if target == from {
+			continue
+		}
+		aliases = append(aliases, schema.ModelAlias{
+			Model:         from,
+			UpstreamModel: target,
+		})
+	}
+
+	return aliases
+}
+
+// resolveModelMapping follows a model through chained mappings and returns
+// the final upstream model. Returns false if the chain loops.
+func resolveModelMapping(mapping map[string]string, model string) (string, bool) {
+	visited := map[string]bool{model: true}
+	current := model
+	for {
+		next, ok := mapping[current]
+		if !ok || next == current {
+			return current, true
+		}
+		if visited[next] {
+			return "", false
+		}
+		visited[next] = true
+		current = next
+	}
+}
@@ -0,0 +1,126 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+func TestResolveModelMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		model   string
+		want    string
+		ok      bool
+	}{
+		{name: "unmapped", mapping: map[string]string{"a": "b"}, model: "x", want: "x", ok: true},
+		{name: "single hop", mapping: map[string]string{"a": "b"}, model: "a", want: "b", ok: true},
+		{name: "multi-hop", mapping: map[string]string{"a": "b", "b": "c", "c": "d"}, model: "a", want: "d", ok: true},
+		{name: "self-map", mapping: map[string]string{"a": "a"}, model: "a", want: "a", ok: true},
+		{name: "chain ending in self-map", mapping: map[string]string{"a": "b", "b": "b"}, model: "a", want: "b", ok: true},
+		{name: "cycle", mapping: map[string]string{"a": "b", "b": "a"}, model: "a", ok: false},
+		{name: "cycle after the first hop", mapping: map[string]string{"a": "b", "b": "c", "c": "b"}, model: "a", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveModelMapping(tt.mapping, tt.model)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("resolveModelMapping(%v, %q) = %q, %v, want %q, %v", tt.mapping, tt.model, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestModelAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  string
+		chained  bool
+		want     []schema.ModelAlias
+		warnings []string // Warning codes
+	}{
+		{name: "empty", mapping: "", chained: true},
+		{name: "empty object", mapping: " {} ", chained: true},
+		{
+			name:    "sorted by public model",
+			mapping: `{"gpt-4":"gpt-4o","claude-3":"claude-3-5-sonnet"}`,
+			chained: true,
+			want:    []schema.ModelAlias{{Model: "claude-3", UpstreamModel: "claude-3-5-sonnet"}, {Model: "gpt-4", UpstreamModel: "gpt-4o"}},
+		},
+		{
+			name:    "multi-hop chain resolved",
+			mapping: `{"a":"b","b":"c","c":"d"}`,
+			chained: true,
+			want:    []schema.ModelAlias{{Model: "a", UpstreamModel: "d"}, {Model: "b", UpstreamModel: "d"}, {Model: "c", UpstreamModel: "d"}},
+		},
+		{
+			// One API applies each entry once
+			name:    "multi-hop chain not chained",
+			mapping: `{"a":"b","b":"c","c":"d"}`,
+			want:    []schema.ModelAlias{{Model: "a", UpstreamModel: "b"}, {Model: "b", UpstreamModel: "c"}, {Model: "c", UpstreamModel: "d"}},
+		},
+		{
+			name:    "self-map skipped",
+			mapping: `{"a":"a","b":"c"}`,
+			chained: true,
+			want:    []schema.ModelAlias{{Model: "b", UpstreamModel: "c"}},
+		},
+		{
+			name:     "cycle skipped",
+			mapping:  `{"a":"b","b":"a","x":"y"}`,
+			chained:  true,
+			want:     []schema.ModelAlias{{Model: "x", UpstreamModel: "y"}},
+			warnings: []string{schema.WarnModelMappingCycle, schema.WarnModelMappingCycle},
+		},
+		{
+			name:    "cycle kept when not chained",
+			mapping: `{"a":"b","b":"a"}`,
+			want:    []schema.ModelAlias{{Model: "a", UpstreamModel: "b"}, {Model: "b", UpstreamModel: "a"}},
+		},
+		{
+			name:     "invalid entries skipped",
+			mapping:  `{"a":1,"b":"","":"c","d":"e"}`,
+			chained:  true,
+			want:     []schema.ModelAlias{{Model: "d", UpstreamModel: "e"}},
+			warnings: []string{schema.WarnInvalidModelMappingEntry, schema.WarnInvalidModelMappingEntry, schema.WarnInvalidModelMappingEntry},
+		},
+		{
+			// The chain stops at the skipped entry
+			name:     "chain through invalid entry",
+			mapping:  `{"a":"b","b":null}`,
+			chained:  true,
+			want:     []schema.ModelAlias{{Model: "a", UpstreamModel: "b"}},
+			warnings: []string{schema.WarnInvalidModelMappingEntry},
+		},
+		{name: "malformed JSON", mapping: `{"a":"b",`, chained: true, warnings: []string{schema.WarnInvalidModelMapping}},
+		{name: "not an object", mapping: `["a","b"]`, chained: true, warnings: []string{schema.WarnInvalidModelMapping}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.NewExportResult()
+			mapping := tt.mapping
+
+			got := ModelAliases(result, Channel{ID: 7, Name: "ch"}, &mapping, tt.chained)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("aliases %+v, want %+v", got, tt.want)
+			}
+
+			var codes []string
+			for _, w := range result.WarningDetails {
+				codes = append(codes, w.Code)
+				if w.OriginalID != 7 || w.Field != "model_mapping" || w.Severity != schema.SeverityError {
+					t.Errorf("warning %+v, want an error about model_mapping of channel 7", w)
+				}
+			}
+			if !reflect.DeepEqual(codes, tt.warnings) {
+				t.Errorf("warnings %v, want %v", codes, tt.warnings)
+			}
+		})
+	}
+
+	if got := ModelAliases(schema.NewExportResult(), Channel{ID: 7}, nil, true); got != nil {
+		t.Errorf("aliases for nil mapping %+v, want none", got)
+	}
+}
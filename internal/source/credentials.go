@@ -0,0 +1,110 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// SplitAWSKey splits a packed AWS key. New API and One API store AWS access
+// keys as "access_key_id|secret_access_key|region"; New API also accepts
+// Bedrock API keys as "api_key|region", for which accessKeyID is empty.
+func SplitAWSKey(key string) (accessKeyID, secret, region string, err error) {
+	parts := strings.Split(strings.TrimSpace(key), "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+		if parts[i] == "" {
+			return "", "", "", fmt.Errorf("part %d of the AWS key is empty", i+1)
+		}
+	}
+
+	switch len(parts) {
+	case 3:
+		return parts[0], parts[1], parts[2], nil
+	case 2:
+		return "", parts[0], parts[1], nil
+	}
+	return "", "", "", fmt.Errorf("expected access_key_id|secret_access_key|region or api_key|region, got %d parts", len(parts))
+}
+
+// IsServiceAccountKey reports whether key looks like a JSON service account
+// key rather than an API key.
+func IsServiceAccountKey(key string) bool {
+	return strings.HasPrefix(strings.TrimSpace(key), "{")
+}
+
+// ServiceAccountProject returns the project ID of a Google service account
+// JSON key. Errors never contain the key itself.
+func ServiceAccountProject(key string) (string, error) {
+	var account struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal([]byte(key), &account); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return "", fmt.Errorf("service account key is not valid JSON (offset %d)", syntaxErr.Offset)
+		}
+		return "", errors.New("service account key is not a JSON object")
+	}
+	if account.ProjectID == "" {
+		return "", errors.New("service account key has no project_id")
+	}
+	return account.ProjectID, nil
+}
+
+// VertexRegions parses a Vertex AI region setting: either a plain region, or
+// a JSON object mapping models to regions, with "default" for all others.
+// Model regions are sorted by model.
+func VertexRegions(s string) (region string, models []schema.ModelRegion, err error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return s, nil, nil
+	}
+
+	var regions map[string]string
+	if err := json.Unmarshal([]byte(s), &regions); err != nil {
+		return "", nil, fmt.Errorf("region setting is not a JSON object of strings: %w", err)
+	}
+	for model, r := range regions {
+		if model == "default" {
+			region = r
+			continue
+		}
+		models = append(models, schema.ModelRegion{Model: model, Region: r})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+	return region, models, nil
+}
+
+// AzureDeployment splits an Azure OpenAI base URL that points at a single
+// deployment (".../openai/deployments/<name>") into the resource URL and the
+// deployment name. Other URLs are returned unchanged with no deployment.
+func AzureDeployment(baseURL string) (resourceURL, deployment string) {
+	const marker = "/openai/deployments/"
+	i := strings.Index(baseURL, marker)
+	if i < 0 {
+		return baseURL, ""
+	}
+	deployment = strings.Trim(baseURL[i+len(marker):], "/")
+	if j := strings.IndexAny(deployment, "/?"); j >= 0 {
+		deployment = deployment[:j]
+	}
+	if deployment == "" {
+		return baseURL, ""
+	}
+	return baseURL[:i], deployment
+}
+
+// NonEmptyOptions returns a pointer to options, or nil if no option is set.
+func NonEmptyOptions(options schema.ProviderOptions) *schema.ProviderOptions {
+	if options.CredentialType == "" && options.Region == "" && len(options.ModelRegions) == 0 &&
+		options.APIVersion == "" && options.Deployment == "" && options.Project == "" &&
+		options.AccountID == "" && options.AccessKeyID == "" {
+		return nil
+	}
+	return &options
+}
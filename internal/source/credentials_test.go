@@ -0,0 +1,133 @@
+package source
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+func TestSplitAWSKey(t *testing.T) {
+	tests := []struct {
+		name                        string
+		key                         string
+		accessKeyID, secret, region string
+		err                         string
+	}{
+		{name: "access key", key: "AKID|S3CR3T|us-east-1", accessKeyID: "AKID", secret: "S3CR3T", region: "us-east-1"},
+		{name: "spaces trimmed", key: " AKID | S3CR3T | us-east-1 \n", accessKeyID: "AKID", secret: "S3CR3T", region: "us-east-1"},
+		{name: "bedrock api key", key: "bedrock-key|us-west-2", secret: "bedrock-key", region: "us-west-2"},
+		{name: "missing region", key: "AKID|S3CR3T|", err: "part 3 of the AWS key is empty"},
+		{name: "empty access key ID", key: "|S3CR3T|us-east-1", err: "part 1 of the AWS key is empty"},
+		{name: "no separator", key: "S3CR3T", err: "got 1 parts"},
+		{name: "extra fields", key: "AKID|S3CR3T|us-east-1|extra", err: "got 4 parts"},
+		{name: "empty", key: "", err: "part 1 of the AWS key is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessKeyID, secret, region, err := SplitAWSKey(tt.key)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				if strings.Contains(err.Error(), "S3CR3T") {
+					t.Errorf("error %q contains the key", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if accessKeyID != tt.accessKeyID || secret != tt.secret || region != tt.region {
+				t.Errorf("got %q, %q, %q, want %q, %q, %q", accessKeyID, secret, region, tt.accessKeyID, tt.secret, tt.region)
+			}
+		})
+	}
+}
+
+func TestVertexRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		region  string
+		models  []schema.ModelRegion
+		err     bool
+	}{
+		{name: "empty", setting: "", region: ""},
+		{name: "plain region", setting: " us-central1 ", region: "us-central1"},
+		{name: "default only", setting: `{"default":"europe-west4"}`, region: "europe-west4"},
+		{
+			name:    "model regions sorted by model",
+			setting: `{"gemini-2.5-pro":"global","default":"us-central1","claude-3-5-sonnet":"us-east5"}`,
+			region:  "us-central1",
+			models: []schema.ModelRegion{
+				{Model: "claude-3-5-sonnet", Region: "us-east5"},
+				{Model: "gemini-2.5-pro", Region: "global"},
+			},
+		},
+		{
+			name:    "model regions without default",
+			setting: `{"gemini-pro":"us-central1"}`,
+			models:  []schema.ModelRegion{{Model: "gemini-pro", Region: "us-central1"}},
+		},
+		{name: "invalid JSON", setting: `{"default":`, err: true},
+		{name: "non-string region", setting: `{"default":1}`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, models, err := VertexRegions(tt.setting)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			if region != tt.region || !reflect.DeepEqual(models, tt.models) {
+				t.Errorf("got %q, %v, want %q, %v", region, models, tt.region, tt.models)
+			}
+		})
+	}
+}
+
+func TestAzureDeployment(t *testing.T) {
+	tests := []struct {
+		baseURL     string
+		resourceURL string
+		deployment  string
+	}{
+		{"https://res.openai.azure.com", "https://res.openai.azure.com", ""},
+		{"https://res.openai.azure.com/", "https://res.openai.azure.com/", ""},
+		{"https://res.openai.azure.com/openai/deployments/gpt-4o", "https://res.openai.azure.com", "gpt-4o"},
+		{"https://res.openai.azure.com/openai/deployments/gpt-4o/", "https://res.openai.azure.com", "gpt-4o"},
+		{"https://res.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-02-01", "https://res.openai.azure.com", "gpt-4o"},
+		{"https://res.openai.azure.com/openai/deployments/gpt-4o?api-version=2024-02-01", "https://res.openai.azure.com", "gpt-4o"},
+		{"https://res.openai.azure.com/openai/deployments/", "https://res.openai.azure.com/openai/deployments/", ""},
+		{"https://gw.example/azure/openai/deployments/mini", "https://gw.example/azure", "mini"},
+	}
+	for _, tt := range tests {
+		resourceURL, deployment := AzureDeployment(tt.baseURL)
+		if resourceURL != tt.resourceURL || deployment != tt.deployment {
+			t.Errorf("AzureDeployment(%q) = %q, %q, want %q, %q", tt.baseURL, resourceURL, deployment, tt.resourceURL, tt.deployment)
+		}
+	}
+}
+
+func TestServiceAccountProject(t *testing.T) {
+	tests := []struct {
+		key     string
+		project string
+		err     string
+	}{
+		{key: `{"type":"service_account","project_id":"my-project"}`, project: "my-project"},
+		{key: `{"type":"service_account"}`, err: "no project_id"},
+		{key: `{"private_key":"S3CR3T"`, err: "not valid JSON"},
+		{key: `["S3CR3T"]`, err: "not a JSON object"},
+	}
+	for _, tt := range tests {
+		project, err := ServiceAccountProject(tt.key)
+		if project != tt.project || (err == nil) != (tt.err == "") || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("ServiceAccountProject(%s) = %q, %v, want %q, %q", tt.key, project, err, tt.project, tt.err)
+		}
+		if err != nil && strings.Contains(err.Error(), "S3CR3T") {
+			t.Errorf("error %q contains the key", err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package database provides database connection helpers shared by sources.
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Type represents the database type.
+type Type string
+
+const (
+	TypeMySQL    Type = "mysql"
+	TypeSQLite   Type = "sqlite"
+	TypePostgres Type = "postgres"
+)
+
+// Config holds database connection configuration.
+type Config struct {
+	Type     Type
+	DSN      string // MySQL: "user:pass@tcp(host:port)/dbname", PostgreSQL: "host=... user=... dbname=..." or URL, SQLite: file path
+	LogLevel logger.LogLevel
+}
+
+// Open opens a GORM database for the given configuration.
+func Open(config Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+
+	switch config.Type {
+	case TypeMySQL:
+		dialector = mysql.Open(config.DSN)
+	case TypeSQLite:
+		dialector = sqlite.Open(config.DSN)
+	case TypePostgres:
+		dialector = postgres.Open(config.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", config.Type)
+	}
+
+	gormConfig := &gorm.Config{
+		Logger: logger.Default.LogMode(config.LogLevel),
+	}
+
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return db, nil
+}
+
+// Close closes the underlying database connection.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping tests the database connection.
+func Ping(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
+}
+
+// snapshotStatements start a read-only transaction with a consistent
+// snapshot on a single connection. SQLite defers taking its read lock until
+// the first read, so a trivial read pins the snapshot immediately.
+var snapshotStatements = map[Type][]string{
+	TypeMySQL: {
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	},
+	TypePostgres: {
+		"BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY",
+	},
+	TypeSQLite: {
+		"BEGIN DEFERRED",
+		"SELECT count(*) FROM sqlite_master",
+	},
+}
+
+// Snapshot runs fn inside a read-only repeatable-read transaction, so every
+// query made through tx sees the database as of the same instant. at is the
+// time the snapshot was taken. The transaction is always rolled back.
+func Snapshot(db *gorm.DB, dbType Type, fn func(tx *gorm.DB, at time.Time) error) error {
+	statements, ok := snapshotStatements[dbType]
+	if !ok {
+		return fmt.Errorf("snapshot not supported for database type: %s", dbType)
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		// The handle passed by Connection accumulates conditions across
+		// calls; a new session makes it reusable for independent queries.
+		conn = conn.Session(&gorm.Session{NewDB: true})
+
+		for _, stmt := range statements {
+			if err := conn.Exec(stmt).Error; err != nil {
+				conn.Exec("ROLLBACK")
+				return fmt.Errorf("failed to start snapshot transaction: %w", err)
+			}
+		}
+		at := time.Now()
+
+		err := fn(conn, at)
+
+		if rbErr := conn.Exec("ROLLBACK").Error; rbErr != nil && err == nil {
+			err = fmt.Errorf("failed to end snapshot transaction: %w", rbErr)
+		}
+		return err
+	})
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder is a GORM logger recording the SQL of every statement, which
+// GORM traces even in DryRun mode.
+type sqlRecorder struct {
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *sqlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// offlineConnector hands out connections that fail every statement, so
+// MySQL and PostgreSQL handles can be opened without a server. In DryRun
+// mode no statement reaches them.
+type offlineConnector struct{}
+
+func (offlineConnector) Connect(context.Context) (driver.Conn, error) { return offlineConn{}, nil }
+func (offlineConnector) Driver() driver.Driver                        { return nil }
+
+type offlineConn struct{}
+
+var errOffline = errors.New("offline connection")
+
+func (offlineConn) Prepare(string) (driver.Stmt, error) { return nil, errOffline }
+func (offlineConn) Close() error                        { return nil }
+func (offlineConn) Begin() (driver.Tx, error)           { return nil, errOffline }
+
+// openDryRun opens a DryRun handle of dbType that records the SQL it would
+// run instead of running it.
+func openDryRun(t *testing.T, dbType Type) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+
+	var dialector gorm.Dialector
+	switch dbType {
+	case TypeMySQL:
+		dialector = mysql.New(mysql.Config{Conn: sql.OpenDB(offlineConnector{}), SkipInitializeWithVersion: true})
+	case TypePostgres:
+		dialector = postgres.New(postgres.Config{Conn: sql.OpenDB(offlineConnector{})})
+	case TypeSQLite:
+		dialector = sqlite.Open(":memory:")
+	}
+
+	recorder := &sqlRecorder{}
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close(db) })
+	return db, recorder
+}
+
+func TestSnapshotStatements(t *testing.T) {
+	tests := []struct {
+		dbType Type
+		want   []string
+	}{
+		{TypeMySQL, []string{
+			"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+			"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+			"SELECT * FROM `channels`",
+			"ROLLBACK",
+		}},
+		{TypePostgres, []string{
+			"BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY",
+			`SELECT * FROM "channels"`,
+			"ROLLBACK",
+		}},
+		{TypeSQLite, []string{
+			"BEGIN DEFERRED",
+			"SELECT count(*) FROM sqlite_master",
+			"SELECT * FROM `channels`",
+			"ROLLBACK",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dbType), func(t *testing.T) {
+			db, recorder := openDryRun(t, tt.dbType)
+
+			err := Snapshot(db, tt.dbType, func(tx *gorm.DB, at time.Time) error {
+				var rows []map[string]interface{}
+				return tx.Table("channels").Find(&rows).Error
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprintf("%q", recorder.statements) != fmt.Sprintf("%q", tt.want) {
+				t.Errorf("statements\n%q\nwant\n%q", recorder.statements, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotUnsupportedType(t *testing.T) {
+	db, recorder := openDryRun(t, TypeSQLite)
+
+	err := Snapshot(db, "oracle", func(*gorm.DB, time.Time) error {
+		t.Error("fn called")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("snapshot of an unsupported type started")
+	}
+	if len(recorder.statements) != 0 {
+		t.Errorf("statements %q, want none", recorder.statements)
+	}
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"sort"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// Route is an enabled ability: a channel serving a model in a group with a
+// priority and weight.
+type Route struct {
+	Group     string
+	Model     string
+	ChannelID int
+	Priority  int64
+	Weight    int
+}
+
+// Ability is a route of a source ability row and whether it is enabled.
+type Ability struct {
+	Route
+	Enabled bool
+}
+
+// ExportAbilities sends a binding for each ability and the failover groups
+// of the enabled ones to sink. Disabled abilities are not routed to, so
+// they are left out of the tiers.
+func ExportAbilities(sink schema.Sink, abilities []Ability) {
+	var routes []Route
+	for _, ab := range abilities {
+		status := "active"
+		if !ab.Enabled {
+			status = "disabled"
+		}
+		sink.AddBinding(schema.Binding{
+			Namespace:  ab.Group,
+			RouteGroup: ab.Group, // Use group as route_group
+			Model:      ab.Model,
+			Status:     status,
+		})
+
+		if ab.Enabled {
+			routes = append(routes, ab.Route)
+		}
+	}
+
+	for _, g := range FailoverGroups(routes) {
+		sink.AddFailoverGroup(g)
+	}
+}
+
+// FailoverGroups groups routes by (group, model) into priority tiers,
+// highest priority first. Groups are sorted by group and model, members by
+// channel ID.
+func FailoverGroups(routes []Route) []schema.FailoverGroup {
+	type key struct{ group, model string }
+	byKey := make(map[key][]Route)
+	var keys []key
+	for _, r := range routes {
+		k := key{r.Group, r.Model}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], r)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	groups := make([]schema.FailoverGroup, 0, len(keys))
+	for _, k := range keys {
+		members := byKey[k]
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].Priority != members[j].Priority {
+				return members[i].Priority > members[j].Priority
+			}
+			return members[i].ChannelID < members[j].ChannelID
+		})
+
+		group := schema.FailoverGroup{Group: k.group, Model: k.model}
+		for _, r := range members {
+			n := len(group.Tiers)
+			if n == 0 || group.Tiers[n-1].Priority != r.Priority {
+				group.Tiers = append(group.Tiers, schema.FailoverTier{Priority: r.Priority})
+				n++
+			}
+			group.Tiers[n-1].Members = append(group.Tiers[n-1].Members, schema.FailoverMember{
+				OriginalID: r.ChannelID,
+				Weight:     r.Weight,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
@@ -0,0 +1,102 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+func TestFailoverGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []Route
+		want   []schema.FailoverGroup
+	}{
+		{name: "none", want: []schema.FailoverGroup{}},
+		{
+			name: "priority tiers, highest first",
+			routes: []Route{
+				{Group: "default", Model: "gpt-4o", ChannelID: 1, Priority: 0, Weight: 1},
+				{Group: "default", Model: "gpt-4o", ChannelID: 2, Priority: 10, Weight: 1},
+				{Group: "default", Model: "gpt-4o", ChannelID: 3, Priority: -5},
+				{Group: "default", Model: "gpt-4o", ChannelID: 4, Priority: 10, Weight: 3},
+			},
+			want: []schema.FailoverGroup{{Group: "default", Model: "gpt-4o", Tiers: []schema.FailoverTier{
+				{Priority: 10, Members: []schema.FailoverMember{{OriginalID: 2, Weight: 1}, {OriginalID: 4, Weight: 3}}},
+				{Priority: 0, Members: []schema.FailoverMember{{OriginalID: 1, Weight: 1}}},
+				{Priority: -5, Members: []schema.FailoverMember{{OriginalID: 3}}},
+			}}},
+		},
+		{
+			// Members of a tier are ordered by channel ID, whatever their weight
+			name: "weight ties",
+			routes: []Route{
+				{Group: "default", Model: "gpt-4o", ChannelID: 9, Weight: 5},
+				{Group: "default", Model: "gpt-4o", ChannelID: 3, Weight: 5},
+				{Group: "default", Model: "gpt-4o", ChannelID: 7},
+				{Group: "default", Model: "gpt-4o", ChannelID: 5},
+			},
+			want: []schema.FailoverGroup{{Group: "default", Model: "gpt-4o", Tiers: []schema.FailoverTier{
+				{Priority: 0, Members: []schema.FailoverMember{
+					{OriginalID: 3, Weight: 5}, {OriginalID: 5}, {OriginalID: 7}, {OriginalID: 9, Weight: 5},
+				}},
+			}}},
+		},
+		{
+			// A single channel still gets a group, so its priority is kept
+			name:   "single channel",
+			routes: []Route{{Group: "vip", Model: "claude-3", ChannelID: 4, Priority: 3, Weight: 2}},
+			want: []schema.FailoverGroup{{Group: "vip", Model: "claude-3", Tiers: []schema.FailoverTier{
+				{Priority: 3, Members: []schema.FailoverMember{{OriginalID: 4, Weight: 2}}},
+			}}},
+		},
+		{
+			name: "groups sorted by group and model",
+			routes: []Route{
+				{Group: "vip", Model: "gpt-4o", ChannelID: 1},
+				{Group: "default", Model: "gpt-4o", ChannelID: 1},
+				{Group: "default", Model: "claude-3", ChannelID: 2},
+			},
+			want: []schema.FailoverGroup{
+				{Group: "default", Model: "claude-3", Tiers: []schema.FailoverTier{{Members: []schema.FailoverMember{{OriginalID: 2}}}}},
+				{Group: "default", Model: "gpt-4o", Tiers: []schema.FailoverTier{{Members: []schema.FailoverMember{{OriginalID: 1}}}}},
+				{Group: "vip", Model: "gpt-4o", Tiers: []schema.FailoverTier{{Members: []schema.FailoverMember{{OriginalID: 1}}}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FailoverGroups(tt.routes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportAbilities(t *testing.T) {
+	result := schema.NewExportResult()
+	ExportAbilities(result, []Ability{
+		{Route: Route{Group: "default", Model: "gpt-4o", ChannelID: 1, Priority: 10}, Enabled: true},
+		{Route: Route{Group: "default", Model: "gpt-4o", ChannelID: 2, Priority: 20}, Enabled: false},
+		{Route: Route{Group: "default", Model: "gpt-4o", ChannelID: 3}, Enabled: true},
+	})
+
+	wantBindings := []schema.Binding{
+		{Namespace: "default", RouteGroup: "default", Model: "gpt-4o", Status: "active"},
+		{Namespace: "default", RouteGroup: "default", Model: "gpt-4o", Status: "disabled"},
+		{Namespace: "default", RouteGroup: "default", Model: "gpt-4o", Status: "active"},
+	}
+	if !reflect.DeepEqual(result.Data.Bindings, wantBindings) {
+		t.Errorf("bindings\n%+v\nwant\n%+v", result.Data.Bindings, wantBindings)
+	}
+
+	// The disabled ability is not routed to
+	wantGroups := []schema.FailoverGroup{{Group: "default", Model: "gpt-4o", Tiers: []schema.FailoverTier{
+		{Priority: 10, Members: []schema.FailoverMember{{OriginalID: 1}}},
+		{Priority: 0, Members: []schema.FailoverMember{{OriginalID: 3}}},
+	}}}
+	if !reflect.DeepEqual(result.Data.FailoverGroups, wantGroups) {
+		t.Errorf("failover groups\n%+v\nwant\n%+v", result.Data.FailoverGroups, wantGroups)
+	}
+}
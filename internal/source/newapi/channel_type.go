@@ -2,6 +2,12 @@
 // Reference: SPEC_newapi_migration_tool.md Appendix B
 package newapi
 
+import (
+	"fmt"
+
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
 // ChannelType represents the channel type enum in New API.
 type ChannelType int
 
@@ -200,3 +206,37 @@ func (t ChannelType) DisplayName() string {
 func MapChannelType(typeID int) (string, bool) {
 	return ChannelType(typeID).ToProviderType()
 }
+
+// TypeMapping is the compiled-in channel type mapping with user-supplied
+// overrides (see source.TypeMap) applied on top. The zero value is the
+// compiled-in mapping.
+type TypeMapping struct {
+	overrides map[ChannelType]source.TypeOverride
+}
+
+// NewTypeMapping creates a mapping with overrides by channel type ID. Type
+// IDs that are not built in must set a provider type.
+func NewTypeMapping(overrides map[int]source.TypeOverride) (TypeMapping, error) {
+	result := make(map[ChannelType]source.TypeOverride, len(overrides))
+	for id, o := range overrides {
+		if _, ok := channelTypeMapping[ChannelType(id)]; !ok && o.Provider == "" {
+			return TypeMapping{}, fmt.Errorf("type %d is not a built-in New API channel type and needs a provider", id)
+		}
+		result[ChannelType(id)] = o
+	}
+	return TypeMapping{overrides: result}, nil
+}
+
+// ProviderType is like ChannelType.ToProviderType, honoring the overrides.
+func (m TypeMapping) ProviderType(t ChannelType) (string, bool) {
+	if o := m.overrides[t]; o.Provider != "" {
+		return o.Provider, true
+	}
+	return t.ToProviderType()
+}
+
+// DefaultBaseURL returns the overridden base URL for channels of type t that
+// have none, or "".
+func (m TypeMapping) DefaultBaseURL(t ChannelType) string {
+	return m.overrides[t].BaseURL
+}
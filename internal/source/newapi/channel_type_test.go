@@ -0,0 +1,83 @@
+package newapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
+func TestNewTypeMapping(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[int]source.TypeOverride
+		err       string
+	}{
+		{name: "none", overrides: nil},
+		{name: "built-in type remapped", overrides: map[int]source.TypeOverride{1: {Provider: "azure"}}},
+		{name: "built-in type with base URL", overrides: map[int]source.TypeOverride{14: {BaseURL: "https://claude.example"}}},
+		{name: "new type with provider", overrides: map[int]source.TypeOverride{999: {Provider: "openai"}}},
+		{
+			name:      "new type without provider",
+			overrides: map[int]source.TypeOverride{999: {BaseURL: "https://x.example"}},
+			err:       "type 999 is not a built-in New API channel type and needs a provider",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTypeMapping(tt.overrides)
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+
+			// The source applies the same rules
+			if err := (&Source{}).SetTypeOverrides(tt.overrides); (err == nil) != (tt.err == "") {
+				t.Errorf("SetTypeOverrides: %v", err)
+			}
+		})
+	}
+}
+
+func TestTypeMappingLookups(t *testing.T) {
+	m, err := NewTypeMapping(map[int]source.TypeOverride{
+		1:   {Provider: "azure"},
+		14:  {BaseURL: "https://claude.example"},
+		999: {Provider: "openai"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typ          ChannelType
+		provider     string
+		known        bool
+		defaultURL   string
+		zeroProvider string
+	}{
+		{ChannelTypeOpenAI, "azure", true, "", "openai"},
+		{ChannelTypeAnthropic, "anthropic", true, "https://claude.example", "anthropic"},
+		{999, "openai", true, "", "custom"},
+		{998, "custom", false, "", "custom"},
+	}
+	for _, tt := range tests {
+		provider, known := m.ProviderType(tt.typ)
+		if provider != tt.provider || known != tt.known {
+			t.Errorf("type %d: ProviderType = %q, %v, want %q, %v", tt.typ, provider, known, tt.provider, tt.known)
+		}
+		if got := m.DefaultBaseURL(tt.typ); got != tt.defaultURL {
+			t.Errorf("type %d: DefaultBaseURL = %q, want %q", tt.typ, got, tt.defaultURL)
+		}
+
+		// The zero value and the compiled-in mapping are unaffected
+		if got, _ := (TypeMapping{}).ProviderType(tt.typ); got != tt.zeroProvider {
+			t.Errorf("type %d: zero TypeMapping.ProviderType = %q, want %q", tt.typ, got, tt.zeroProvider)
+		}
+		if got, _ := tt.typ.ToProviderType(); got != tt.zeroProvider {
+			t.Errorf("type %d: ToProviderType = %q, want %q", tt.typ, got, tt.zeroProvider)
+		}
+	}
+}
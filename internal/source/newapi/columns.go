@@ -0,0 +1,158 @@
+package newapi
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// tabler is a model with a table name.
+type tabler interface {
+	TableName() string
+}
+
+// schemaModels lists the models whose columns are detected.
+var schemaModels = []tabler{&Channel{}, &Token{}, &User{}, &Ability{}, &Redemption{}}
+
+// schemaMarker is a column that first appeared in a New API release.
+type schemaMarker struct {
+	table   string
+	column  string
+	version string
+}
+
+// schemaMarkers lists columns added by New API releases, oldest first. The
+// versions are those of the release that introduced the feature and are only
+// precise enough to tell database generations apart.
+var schemaMarkers = []schemaMarker{
+	{"users", "linux_do_id", "v0.3.0"},
+	{"channels", "tag", "v0.4.0"},
+	{"channels", "param_override", "v0.5.0"},
+	{"redemptions", "expired_time", "v0.6.0"},
+	{"channels", "header_override", "v0.8.0"},
+	{"channels", "channel_info", "v0.9.0"},
+	{"channels", "settings", "v0.9.0"},
+	{"users", "stripe_customer", "v0.9.5"},
+	{"tokens", "cross_group_retry", "v0.10.0"},
+}
+
+// DetectSchema reads the columns of the New API tables through the migrator.
+// Afterwards, queries select only the model columns that exist, so databases
+// of older releases (which lack newer columns) can be read.
+func (c *Connector) DetectSchema() error {
+	migrator := c.db.Migrator()
+	columns := make(map[string]map[string]bool, len(schemaModels))
+	selects := make(map[string][]string, len(schemaModels))
+	var missing []string
+
+	for _, model := range schemaModels {
+		table := model.TableName()
+		if !migrator.HasTable(table) {
+			continue
+		}
+
+		columnTypes, err := migrator.ColumnTypes(table)
+		if err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		existing := make(map[string]bool, len(columnTypes))
+		for _, ct := range columnTypes {
+			existing[strings.ToLower(ct.Name())] = true
+		}
+
+		stmt := &gorm.Statement{DB: c.db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model of %s: %w", table, err)
+		}
+		var selected []string
+		for _, name := range stmt.Schema.DBNames {
+			if existing[name] {
+				selected = append(selected, name)
+			} else {
+				missing = append(missing, table+"."+name)
+			}
+		}
+
+		columns[table] = existing
+		selects[table] = selected
+	}
+
+	c.columns = columns
+	c.selects = selects
+	c.missing = missing
+	return nil
+}
+
+// HasColumn reports whether table has column. Before DetectSchema, all
+// columns are assumed to exist.
+func (c *Connector) HasColumn(table, column string) bool {
+	if c.columns == nil {
+		return true
+	}
+	return c.columns[table][column]
+}
+
+// HasTable reports whether the database has table. Before DetectSchema, all
+// tables are assumed to exist.
+func (c *Connector) HasTable(table string) bool {
+	if c.columns == nil {
+		return true
+	}
+	_, ok := c.columns[table]
+	return ok
+}
+
+// SchemaVersion infers the range of New API versions whose schema matches
+// the detected columns, e.g. ">=v0.8.0 <v0.9.0". It returns "unknown" before
+// DetectSchema.
+func (c *Connector) SchemaVersion() string {
+	if c.columns == nil {
+		return "unknown"
+	}
+
+	newest := -1
+	for i, m := range schemaMarkers {
+		if c.columns[m.table][m.column] {
+			newest = i
+		}
+	}
+	if newest < 0 {
+		return "<" + schemaMarkers[0].version
+	}
+
+	lower := schemaMarkers[newest].version
+	for _, m := range schemaMarkers[newest+1:] {
+		if m.version != lower {
+			return ">=" + lower + " <" + m.version
+		}
+	}
+	return ">=" + lower
+}
+
+// MissingColumns returns the model columns absent from the database, as
+// "table.column" in model order. Databases of older New API releases lack
+// newer columns; their fields are exported empty. Columns of missing tables
+// are not listed. It returns nil before DetectSchema.
+func (c *Connector) MissingColumns() []string {
+	return c.missing
+}
+
+// table returns a query on the table of model. Tables without a deleted_at
+// column are queried unscoped, since GORM would otherwise filter on it.
+func (c *Connector) table(model tabler) *gorm.DB {
+	db := c.db.Model(model)
+	if !c.HasColumn(model.TableName(), "deleted_at") {
+		db = db.Unscoped()
+	}
+	return db
+}
+
+// from is like table but selects only the model columns that exist.
+func (c *Connector) from(model tabler) *gorm.DB {
+	db := c.table(model)
+	if selected, ok := c.selects[model.TableName()]; ok {
+		db = db.Select(selected)
+	}
+	return db
+}
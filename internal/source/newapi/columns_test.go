@@ -0,0 +1,250 @@
+package newapi
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
+	"github.com/EZ-Api/exporter/internal/source/database"
+	"gorm.io/gorm/logger"
+)
+
+// droppedColumns are columns added by later New API releases, removed to
+// build the database of an older release.
+var droppedColumns = []struct {
+	model  tabler
+	column string
+}{
+	{&Channel{}, "tag"},
+	{&Channel{}, "setting"},
+	{&Channel{}, "param_override"},
+	{&Channel{}, "header_override"},
+	{&Channel{}, "channel_info"},
+	{&Channel{}, "settings"},
+	{&Token{}, "cross_group_retry"},
+	{&Token{}, "deleted_at"},
+	{&User{}, "linux_do_id"},
+	{&User{}, "stripe_customer"},
+}
+
+// newOldSchemaDB creates a New API database with one channel, user and token,
+// then drops droppedColumns from it. It returns the database path.
+func newOldSchemaDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "new_api.db")
+	c, err := NewSQLiteConnector(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	db := c.GetDB()
+
+	if err := db.AutoMigrate(&Channel{}, &Token{}, &User{}, &Ability{}, &Redemption{}); err != nil {
+		t.Fatal(err)
+	}
+
+	text := func(s string) *string { return &s }
+	channel := Channel{
+		ID: 1, Type: int(ChannelTypeOpenAI), Key: "sk-channel", Name: "openai", Status: 1,
+		Models: "gpt-4o", Group: "default",
+		Tag: text("tagged"), HeaderOverride: text(`{"Authorization":"Bearer x"}`), OtherSettings: `{"azure":1}`,
+	}
+	user := User{ID: 1, Username: "alice", Status: 1, Role: 1, Group: "default", AffCode: "a1", StripeCustomer: "cus_1"}
+	token := Token{ID: 1, UserID: 1, Key: "tok-alice", Name: "default", Status: 1, CrossGroupRetry: true}
+	for _, row := range []interface{}{&channel, &user, &token} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, d := range droppedColumns {
+		if err := db.Migrator().DropColumn(d.model, d.column); err != nil {
+			t.Fatalf("drop %s.%s: %v", d.model.TableName(), d.column, err)
+		}
+	}
+	return path
+}
+
+func TestDetectSchemaOldDatabase(t *testing.T) {
+	path := newOldSchemaDB(t)
+
+	c, err := NewSQLiteConnector(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.DetectSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := map[string]bool{}
+	for _, name := range c.MissingColumns() {
+		missing[name] = true
+	}
+	if len(missing) != len(droppedColumns) {
+		t.Errorf("missing columns %v, want the %d dropped columns", c.MissingColumns(), len(droppedColumns))
+	}
+	for _, d := range droppedColumns {
+		if name := d.model.TableName() + "." + d.column; !missing[name] {
+			t.Errorf("%s not reported missing", name)
+		}
+	}
+	if c.HasColumn("tokens", "deleted_at") || !c.HasColumn("tokens", "key") {
+		t.Error("HasColumn does not match the database")
+	}
+
+	// Fields of missing columns read as empty
+	channels, err := c.GetAllChannels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0].Tag != nil || channels[0].HeaderOverride != nil || channels[0].OtherSettings != "" {
+		t.Errorf("channels %+v, want one channel without tag, header override and settings", channels)
+	}
+	if channels[0].Key != "sk-channel" {
+		t.Errorf("channel key %q, want sk-channel", channels[0].Key)
+	}
+	tokens, err := c.GetAllTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].CrossGroupRetry {
+		t.Errorf("tokens %+v, want one token without cross-group retry", tokens)
+	}
+	users, err := c.GetAllUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].StripeCustomer != "" {
+		t.Errorf("users %+v, want one user without Stripe customer", users)
+	}
+}
+
+func TestExportOldDatabase(t *testing.T) {
+	path := newOldSchemaDB(t)
+
+	s := &Source{}
+	if err := s.Connect(database.Config{Type: database.TypeSQLite, DSN: path, LogLevel: logger.Silent}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// redemptions.expired_time is the newest marker left
+	version, err := s.DetectVersion()
+	if want := ">=v0.6.0 <v0.8.0"; err != nil || version != want {
+		t.Errorf("DetectVersion = %q, %v, want %q", version, err, want)
+	}
+
+	result := schema.NewExportResult()
+	if err := s.Export(source.ExportOptions{IncludeTokens: true}, result); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	if got := result.GetSummary(); got.Providers != 1 || got.Masters != 1 || got.Keys != 1 {
+		t.Errorf("summary %+v, want one provider, master and key", got)
+	}
+	if !reflect.DeepEqual(result.Source.MissingColumns, s.connector.MissingColumns()) || len(result.Source.MissingColumns) != len(droppedColumns) {
+		t.Errorf("source.missing_columns %v, want the %d dropped columns", result.Source.MissingColumns, len(droppedColumns))
+	}
+	if result.Source.Version != version {
+		t.Errorf("source.version %q, want %q", result.Source.Version, version)
+	}
+	if violations := result.Validate(); len(violations) > 0 {
+		t.Errorf("export is invalid: %v", violations)
+	}
+}
+
+func TestExportMissingTables(t *testing.T) {
+	path := newOldSchemaDB(t)
+	c, err := NewSQLiteConnector(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"redemptions", "abilities"} {
+		if err := c.GetDB().Migrator().DropTable(table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Close()
+
+	s := &Source{}
+	if err := s.Connect(database.Config{Type: database.TypeSQLite, DSN: path, LogLevel: logger.Silent}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if stats, err := s.Stats(); err != nil || stats.Abilities != 0 {
+		t.Errorf("Stats = %+v, %v, want no abilities", stats, err)
+	}
+
+	result := schema.NewExportResult()
+	options := source.ExportOptions{IncludeTokens: true, IncludeAbilities: true, IncludeRedemptions: true}
+	if err := s.Export(options, result); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	var missing []string
+	for _, w := range result.WarningDetails {
+		if w.Code == schema.WarnMissingTable {
+			missing = append(missing, w.Field)
+		}
+	}
+	if want := []string{"abilities", "redemptions"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("%s warnings for %v, want %v", schema.WarnMissingTable, missing, want)
+	}
+	if got := result.GetSummary(); got.Providers != 1 || got.Masters != 1 || got.Bindings != 0 || got.Redemptions != 0 {
+		t.Errorf("summary %+v, want one provider and master and nothing else", got)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	// columns returns the detected columns of a database with the given
+	// "table.column" markers
+	columns := func(markers ...string) map[string]map[string]bool {
+		detected := map[string]map[string]bool{"channels": {"id": true}, "users": {"id": true}, "tokens": {"id": true}}
+		for _, marker := range markers {
+			table, column, _ := strings.Cut(marker, ".")
+			if detected[table] == nil {
+				detected[table] = map[string]bool{}
+			}
+			detected[table][column] = true
+		}
+		return detected
+	}
+
+	tests := []struct {
+		name    string
+		columns map[string]map[string]bool
+		want    string
+	}{
+		{"not detected", nil, "unknown"},
+		{"no markers", columns(), "<v0.3.0"},
+		{"oldest marker", columns("users.linux_do_id"), ">=v0.3.0 <v0.4.0"},
+		{"middle", columns("users.linux_do_id", "channels.tag", "channels.param_override", "redemptions.expired_time", "channels.header_override"), ">=v0.8.0 <v0.9.0"},
+		{"markers of one release", columns("channels.channel_info", "channels.settings"), ">=v0.9.0 <v0.9.5"},
+		{"newest marker", columns("channels.settings", "users.stripe_customer", "tokens.cross_group_retry"), ">=v0.10.0"},
+		{"newest present marker wins", columns("users.linux_do_id", "users.stripe_customer"), ">=v0.9.5 <v0.10.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Connector{columns: tt.columns}
+			if got := c.SchemaVersion(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSchemaCurrentDatabase(t *testing.T) {
+	c := newTestConnector(t)
+	if err := c.DetectSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if missing := c.MissingColumns(); missing != nil {
+		t.Errorf("missing columns %v, want none", missing)
+	}
+}
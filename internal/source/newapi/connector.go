@@ -3,54 +3,42 @@ package newapi
 
 import (
 	"fmt"
+	"time"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/sqlite"
+	"github.com/EZ-Api/exporter/internal/source/database"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 // ConnectorType represents the database type.
-type ConnectorType string
+type ConnectorType = database.Type
 
 const (
-	ConnectorTypeMySQL  ConnectorType = "mysql"
-	ConnectorTypeSQLite ConnectorType = "sqlite"
+	ConnectorTypeMySQL    = database.TypeMySQL
+	ConnectorTypeSQLite   = database.TypeSQLite
+	ConnectorTypePostgres = database.TypePostgres
 )
 
 // ConnectorConfig holds database connection configuration.
-type ConnectorConfig struct {
-	Type     ConnectorType
-	DSN      string // MySQL: "user:pass@tcp(host:port)/dbname", SQLite: file path
-	LogLevel logger.LogLevel
-}
+type ConnectorConfig = database.Config
 
 // Connector provides database access for New API.
 type Connector struct {
 	db     *gorm.DB
 	config ConnectorConfig
+
+	// Detected schema (see DetectSchema); nil until detected
+	columns map[string]map[string]bool // table -> existing columns
+	selects map[string][]string        // table -> model columns that exist
+	missing []string                   // model columns that do not exist, "table.column"
 }
 
 // NewConnector creates a new database connector.
 func NewConnector(config ConnectorConfig) (*Connector, error) {
-	var dialector gorm.Dialector
-
-	switch config.Type {
-	case ConnectorTypeMySQL:
-		dialector = mysql.Open(config.DSN)
-	case ConnectorTypeSQLite:
-		dialector = sqlite.Open(config.DSN)
-	default:
-		return nil, fmt.Errorf("unsupported database type: %s", config.Type)
-	}
-
-	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(config.LogLevel),
-	}
-
-	db, err := gorm.Open(dialector, gormConfig)
+	db, err := database.Open(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
 	}
 
 	return &Connector{
@@ -77,13 +65,29 @@ func NewSQLiteConnector(path string) (*Connector, error) {
 	})
 }
 
+// NewPostgresConnector creates a PostgreSQL connector with the given DSN.
+func NewPostgresConnector(dsn string) (*Connector, error) {
+	return NewConnector(ConnectorConfig{
+		Type:     ConnectorTypePostgres,
+		DSN:      dsn,
+		LogLevel: logger.Silent,
+	})
+}
+
 // Close closes the database connection.
 func (c *Connector) Close() error {
-	sqlDB, err := c.db.DB()
-	if err != nil {
-		return err
+	return database.Close(c.db)
+}
+
+// withDB returns a connector that queries through db (e.g. a transaction).
+func (c *Connector) withDB(db *gorm.DB) *Connector {
+	return &Connector{
+		db:      db,
+		config:  c.config,
+		columns: c.columns,
+		selects: c.selects,
+		missing: c.missing,
 	}
-	return sqlDB.Close()
 }
 
 // GetDB returns the underlying GORM database instance.
@@ -98,14 +102,52 @@ func (c *Connector) GetDB() *gorm.DB {
 // GetAllChannels retrieves all channels from the database.
 func (c *Connector) GetAllChannels() ([]Channel, error) {
 	var channels []Channel
-	err := c.db.Find(&channels).Error
+	err := c.from(&Channel{}).Find(&channels).Error
 	return channels, err
 }
 
+// EachChannelBatch reads all channels in batches ordered by ID and calls fn for each batch.
+func (c *Connector) EachChannelBatch(batchSize int, fn func([]Channel) error) error {
+	var channels []Channel
+	return c.from(&Channel{}).FindInBatches(&channels, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(channels)
+	}).Error
+}
+
+// EachChannelBatchSince reads channels created or tested at or after since
+// (Unix time) in batches ordered by ID and calls fn for each batch.
+func (c *Connector) EachChannelBatchSince(since int64, batchSize int, fn func([]Channel) error) error {
+	var channels []Channel
+	return c.from(&Channel{}).Where("created_time >= ? OR test_time >= ?", since, since).
+		FindInBatches(&channels, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(channels)
+		}).Error
+}
+
+// GetChannelNameCounts returns how many channels use each channel name.
+func (c *Connector) GetChannelNameCounts() (map[string]int, error) {
+	var names []string
+	if err := c.table(&Channel{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name]++
+	}
+	return counts, nil
+}
+
+// GetChannelIDs returns the IDs of all channels.
+func (c *Connector) GetChannelIDs() ([]int, error) {
+	var ids []int
+	err := c.table(&Channel{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
 // GetChannelByID retrieves a channel by ID.
 func (c *Connector) GetChannelByID(id int) (*Channel, error) {
 	var channel Channel
-	err := c.db.First(&channel, id).Error
+	err := c.from(&Channel{}).First(&channel, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -115,14 +157,14 @@ func (c *Connector) GetChannelByID(id int) (*Channel, error) {
 // GetActiveChannels retrieves all active (enabled) channels.
 func (c *Connector) GetActiveChannels() ([]Channel, error) {
 	var channels []Channel
-	err := c.db.Where("status = ?", ChannelStatusEnabled).Find(&channels).Error
+	err := c.from(&Channel{}).Where("status = ?", ChannelStatusEnabled).Find(&channels).Error
 	return channels, err
 }
 
 // CountChannels returns the total number of channels.
 func (c *Connector) CountChannels() (int64, error) {
 	var count int64
-	err := c.db.Model(&Channel{}).Count(&count).Error
+	err := c.table(&Channel{}).Count(&count).Error
 	return count, err
 }
 
@@ -133,38 +175,103 @@ func (c *Connector) CountChannels() (int64, error) {
 // GetAllTokens retrieves all tokens from the database.
 func (c *Connector) GetAllTokens() ([]Token, error) {
 	var tokens []Token
-	err := c.db.Find(&tokens).Error
+	err := c.from(&Token{}).Find(&tokens).Error
 	return tokens, err
 }
 
 // GetTokenByID retrieves a token by ID.
 func (c *Connector) GetTokenByID(id int) (*Token, error) {
 	var token Token
-	err := c.db.First(&token, id).Error
+	err := c.from(&Token{}).First(&token, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &token, nil
 }
 
+// EachTokenBatchByUser reads all tokens in batches ordered by (user_id, id)
+// and calls fn for each batch. It uses keyset pagination, so each batch is a
+// single indexed range query regardless of how deep into the table it is.
+func (c *Connector) EachTokenBatchByUser(batchSize int, fn func([]Token) error) error {
+	return c.eachTokenBatchByUser(batchSize, fn)
+}
+
+// EachTokenBatchByUserSince is like EachTokenBatchByUser but only reads
+// tokens created or accessed at or after since (Unix time).
+func (c *Connector) EachTokenBatchByUserSince(since int64, batchSize int, fn func([]Token) error) error {
+	return c.eachTokenBatchByUser(batchSize, fn, "(created_time >= ? OR accessed_time >= ?)", since, since)
+}
+
+// eachTokenBatchByUser pages through tokens matching the optional condition
+// (a query string followed by its arguments).
+func (c *Connector) eachTokenBatchByUser(batchSize int, fn func([]Token) error, cond ...interface{}) error {
+	lastUserID, lastID := 0, 0
+	first := true
+
+	for {
+		var tokens []Token
+		query := c.from(&Token{}).Order("user_id").Order("id").Limit(batchSize)
+		if len(cond) > 0 {
+			query = query.Where(cond[0], cond[1:]...)
+		}
+		if !first {
+			query = query.Where("(user_id > ? OR (user_id = ? AND id > ?))", lastUserID, lastUserID, lastID)
+		}
+		if err := query.Find(&tokens).Error; err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		if err := fn(tokens); err != nil {
+			return err
+		}
+
+		if len(tokens) < batchSize {
+			return nil
+		}
+		last := tokens[len(tokens)-1]
+		lastUserID, lastID = last.UserID, last.ID
+		first = false
+	}
+}
+
+// GetTokenIDs returns the IDs of all tokens that are not soft-deleted.
+func (c *Connector) GetTokenIDs() ([]int, error) {
+	var ids []int
+	err := c.table(&Token{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetTokensDeletedSince retrieves tokens soft-deleted at or after since.
+func (c *Connector) GetTokensDeletedSince(since time.Time) ([]Token, error) {
+	var tokens []Token
+	if !c.HasColumn("tokens", "deleted_at") {
+		return nil, nil
+	}
+	err := c.from(&Token{}).Unscoped().Where("deleted_at >= ?", since).Order("id").Find(&tokens).Error
+	return tokens, err
+}
+
 // GetTokensByUserID retrieves all tokens for a user.
 func (c *Connector) GetTokensByUserID(userID int) ([]Token, error) {
 	var tokens []Token
-	err := c.db.Where("user_id = ?", userID).Find(&tokens).Error
+	err := c.from(&Token{}).Where("user_id = ?", userID).Find(&tokens).Error
 	return tokens, err
 }
 
 // GetActiveTokens retrieves all active (enabled) tokens.
 func (c *Connector) GetActiveTokens() ([]Token, error) {
 	var tokens []Token
-	err := c.db.Where("status = ?", TokenStatusEnabled).Find(&tokens).Error
+	err := c.from(&Token{}).Where("status = ?", TokenStatusEnabled).Find(&tokens).Error
 	return tokens, err
 }
 
 // CountTokens returns the total number of tokens.
 func (c *Connector) CountTokens() (int64, error) {
 	var count int64
-	err := c.db.Model(&Token{}).Count(&count).Error
+	err := c.table(&Token{}).Count(&count).Error
 	return count, err
 }
 
@@ -175,14 +282,14 @@ func (c *Connector) CountTokens() (int64, error) {
 // GetAllUsers retrieves all users from the database.
 func (c *Connector) GetAllUsers() ([]User, error) {
 	var users []User
-	err := c.db.Find(&users).Error
+	err := c.from(&User{}).Find(&users).Error
 	return users, err
 }
 
 // GetUserByID retrieves a user by ID.
 func (c *Connector) GetUserByID(id int) (*User, error) {
 	var user User
-	err := c.db.First(&user, id).Error
+	err := c.from(&User{}).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -192,21 +299,70 @@ func (c *Connector) GetUserByID(id int) (*User, error) {
 // GetActiveUsers retrieves all active (enabled) users.
 func (c *Connector) GetActiveUsers() ([]User, error) {
 	var users []User
-	err := c.db.Where("status = ?", UserStatusEnabled).Find(&users).Error
+	err := c.from(&User{}).Where("status = ?", UserStatusEnabled).Find(&users).Error
+	return users, err
+}
+
+// GetAdminUsers retrieves all admin and root users ordered by ID.
+func (c *Connector) GetAdminUsers() ([]User, error) {
+	var users []User
+	err := c.from(&User{}).Where("role >= ?", RoleAdminUser).Order("id").Find(&users).Error
 	return users, err
 }
 
 // GetUsersWithTokens retrieves all users who have at least one token.
 func (c *Connector) GetUsersWithTokens() ([]User, error) {
 	var users []User
-	err := c.db.Where("id IN (SELECT DISTINCT user_id FROM tokens)").Find(&users).Error
+	err := c.from(&User{}).Where("id IN (SELECT DISTINCT user_id FROM tokens)").Find(&users).Error
+	return users, err
+}
+
+// EachUserWithTokensBatch reads users who have at least one token in batches
+// ordered by ID and calls fn for each batch.
+func (c *Connector) EachUserWithTokensBatch(batchSize int, fn func([]User) error) error {
+	var users []User
+	return c.from(&User{}).Where("id IN (SELECT DISTINCT user_id FROM tokens)").
+		FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(users)
+		}).Error
+}
+
+// EachUserWithTokensBatchSince reads users who have a token created or
+// accessed at or after since (Unix time) in batches ordered by ID and calls
+// fn for each batch.
+func (c *Connector) EachUserWithTokensBatchSince(since int64, batchSize int, fn func([]User) error) error {
+	var users []User
+	cond := "created_time >= ? OR accessed_time >= ?"
+	if c.HasColumn("tokens", "deleted_at") {
+		cond = "deleted_at IS NULL AND (" + cond + ")"
+	}
+	return c.from(&User{}).Where("id IN (SELECT DISTINCT user_id FROM tokens WHERE "+cond+")", since, since).
+		FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(users)
+		}).Error
+}
+
+// GetUserIDs returns the IDs of all users that are not soft-deleted.
+func (c *Connector) GetUserIDs() ([]int, error) {
+	var ids []int
+	err := c.table(&User{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetUsersDeletedSince retrieves users soft-deleted at or after since.
+func (c *Connector) GetUsersDeletedSince(since time.Time) ([]User, error) {
+	var users []User
+	if !c.HasColumn("users", "deleted_at") {
+		return nil, nil
+	}
+	err := c.from(&User{}).Unscoped().Where("deleted_at >= ?", since).Order("id").Find(&users).Error
 	return users, err
 }
 
 // CountUsers returns the total number of users.
 func (c *Connector) CountUsers() (int64, error) {
 	var count int64
-	err := c.db.Model(&User{}).Count(&count).Error
+	err := c.table(&User{}).Count(&count).Error
 	return count, err
 }
 
@@ -217,42 +373,66 @@ func (c *Connector) CountUsers() (int64, error) {
 // GetAllAbilities retrieves all abilities from the database.
 func (c *Connector) GetAllAbilities() ([]Ability, error) {
 	var abilities []Ability
-	err := c.db.Find(&abilities).Error
+	err := c.from(&Ability{}).Find(&abilities).Error
 	return abilities, err
 }
 
 // GetAbilitiesByChannelID retrieves all abilities for a channel.
 func (c *Connector) GetAbilitiesByChannelID(channelID int) ([]Ability, error) {
 	var abilities []Ability
-	err := c.db.Where("channel_id = ?", channelID).Find(&abilities).Error
+	err := c.from(&Ability{}).Where("channel_id = ?", channelID).Find(&abilities).Error
 	return abilities, err
 }
 
 // GetAbilitiesByGroup retrieves all abilities for a group.
+// "group" is a reserved word, so the column is quoted by the dialect
+// (backticks on MySQL/SQLite, double quotes on PostgreSQL).
 func (c *Connector) GetAbilitiesByGroup(group string) ([]Ability, error) {
 	var abilities []Ability
-	err := c.db.Where("`group` = ?", group).Find(&abilities).Error
+	err := c.from(&Ability{}).Where(clause.Eq{Column: clause.Column{Name: "group"}, Value: group}).Find(&abilities).Error
 	return abilities, err
 }
 
 // CountAbilities returns the total number of abilities.
 func (c *Connector) CountAbilities() (int64, error) {
 	var count int64
-	err := c.db.Model(&Ability{}).Count(&count).Error
+	if !c.HasTable(Ability{}.TableName()) {
+		return 0, nil
+	}
+	err := c.table(&Ability{}).Count(&count).Error
 	return count, err
 }
 
+// ============================================
+// Redemption Operations
+// ============================================
+
+// EachRedemptionBatch reads all redemption codes in batches ordered by ID
+// and calls fn for each batch.
+func (c *Connector) EachRedemptionBatch(batchSize int, fn func([]Redemption) error) error {
+	var redemptions []Redemption
+	return c.from(&Redemption{}).FindInBatches(&redemptions, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(redemptions)
+	}).Error
+}
+
+// EachRedemptionBatchSince reads redemption codes created or redeemed at or
+// after since (Unix time) in batches ordered by ID and calls fn for each batch.
+func (c *Connector) EachRedemptionBatchSince(since int64, batchSize int, fn func([]Redemption) error) error {
+	var redemptions []Redemption
+	return c.from(&Redemption{}).Where("created_time >= ? OR redeemed_time >= ?", since, since).
+		FindInBatches(&redemptions, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(redemptions)
+		}).Error
+}
+
 // ============================================
 // Utility Methods
 // ============================================
 
 // Ping tests the database connection.
 func (c *Connector) Ping() error {
-	sqlDB, err := c.db.DB()
-	if err != nil {
-		return err
-	}
-	return sqlDB.Ping()
+	return database.Ping(c.db)
 }
 
 // GetDatabaseStats returns database statistics.
@@ -0,0 +1,250 @@
+package newapi
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestConnector opens a connector on a fresh SQLite database with the
+// New API tables.
+func newTestConnector(tb testing.TB) *Connector {
+	tb.Helper()
+
+	c, err := NewSQLiteConnector(filepath.Join(tb.TempDir(), "new_api.db"))
+	if err != nil {
+		tb.Fatalf("open: %v", err)
+	}
+	tb.Cleanup(func() { c.Close() })
+
+	if err := c.GetDB().AutoMigrate(&Channel{}, &Token{}, &User{}, &Ability{}, &Redemption{}); err != nil {
+		tb.Fatalf("migrate: %v", err)
+	}
+	return c
+}
+
+// seedTokens inserts count tokens spread unevenly over users, in an order
+// that interleaves users so that token IDs are not grouped by user. It
+// returns the number of distinct users.
+func seedTokens(tb testing.TB, c *Connector, count int) int {
+	tb.Helper()
+
+	const users = 37
+	tokens := make([]Token, count)
+	for i := range tokens {
+		// Quadratic spread: low user IDs get many more tokens than high ones
+		u := (i * i) % users
+		tokens[i] = Token{
+			UserID:      u + 1,
+			Key:         fmt.Sprintf("sk-%045d", i),
+			Name:        fmt.Sprintf("token-%d", i),
+			CreatedTime: int64(i),
+		}
+	}
+	if err := c.GetDB().CreateInBatches(tokens, 500).Error; err != nil {
+		tb.Fatalf("seed tokens: %v", err)
+	}
+
+	seen := map[int]bool{}
+	for _, t := range tokens {
+		seen[t.UserID] = true
+	}
+	return len(seen)
+}
+
+func TestEachTokenBatchByUser(t *testing.T) {
+	c := newTestConnector(t)
+	const count = 3000
+	seedTokens(t, c, count)
+
+	all, err := c.GetAllTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]int, len(all))
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].UserID != all[j].UserID {
+			return all[i].UserID < all[j].UserID
+		}
+		return all[i].ID < all[j].ID
+	})
+	for i, tok := range all {
+		want[i] = tok.ID
+	}
+
+	for _, batchSize := range []int{1, 7, 100, 999, 1000, count, count + 1} {
+		t.Run(fmt.Sprintf("batch=%d", batchSize), func(t *testing.T) {
+			var got []int
+			spanning := 0
+			prevLastUser := -1
+			err := c.EachTokenBatchByUser(batchSize, func(batch []Token) error {
+				if len(batch) == 0 || len(batch) > batchSize {
+					t.Fatalf("batch of %d tokens, batch size %d", len(batch), batchSize)
+				}
+				if batch[0].UserID == prevLastUser {
+					spanning++
+				}
+				prevLastUser = batch[len(batch)-1].UserID
+				for _, tok := range batch {
+					got = append(got, tok.ID)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d tokens, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("token %d: got ID %d, want %d", i, got[i], want[i])
+				}
+			}
+			if batchSize < count && spanning == 0 {
+				t.Errorf("no user's tokens spanned a batch boundary; fixture does not cover that case")
+			}
+		})
+	}
+}
+
+func TestEachTokenBatchByUserSince(t *testing.T) {
+	c := newTestConnector(t)
+	seedTokens(t, c, 500)
+
+	const since = 400
+	var got []int
+	err := c.EachTokenBatchByUserSince(since, 9, func(batch []Token) error {
+		for _, tok := range batch {
+			if tok.CreatedTime < since {
+				t.Errorf("token %d created at %d, before %d", tok.ID, tok.CreatedTime, since)
+			}
+			got = append(got, tok.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 100 {
+		t.Errorf("got %d tokens, want 100", len(got))
+	}
+}
+
+// BenchmarkEachTokenBatchByUser compares the per-user token queries used
+// before keyset pagination with the keyset path.
+func BenchmarkEachTokenBatchByUser(b *testing.B) {
+	c := newTestConnector(b)
+	const count = 5000
+	seedTokens(b, c, count)
+
+	b.Run("per-user", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var userIDs []int
+			if err := c.table(&Token{}).Distinct("user_id").Order("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+				b.Fatal(err)
+			}
+			n := 0
+			for _, id := range userIDs {
+				tokens, err := c.GetTokensByUserID(id)
+				if err != nil {
+					b.Fatal(err)
+				}
+				n += len(tokens)
+			}
+			if n != count {
+				b.Fatalf("read %d tokens, want %d", n, count)
+			}
+		}
+	})
+
+	b.Run("keyset", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			n := 0
+			err := c.EachTokenBatchByUser(1000, func(batch []Token) error {
+				n += len(batch)
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if n != count {
+				b.Fatalf("read %d tokens, want %d", n, count)
+			}
+		}
+	})
+}
+
+// sqlRecorder is a GORM logger recording the SQL of every statement, which
+// GORM traces even in DryRun mode.
+type sqlRecorder struct {
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *sqlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// newDryRunConnector returns a connector of dbType that records the SQL it
+// would run instead of running it. The MySQL and PostgreSQL handles point at
+// a closed port and are never connected.
+func newDryRunConnector(t *testing.T, dbType ConnectorType) (*Connector, *sqlRecorder) {
+	t.Helper()
+
+	var dialector gorm.Dialector
+	switch dbType {
+	case ConnectorTypeMySQL:
+		dialector = mysql.New(mysql.Config{DSN: "user@tcp(127.0.0.1:1)/new_api", SkipInitializeWithVersion: true})
+	case ConnectorTypePostgres:
+		dialector = postgres.Open("host=127.0.0.1 port=1 user=user dbname=new_api")
+	case ConnectorTypeSQLite:
+		dialector = sqlite.Open(":memory:")
+	}
+
+	recorder := &sqlRecorder{}
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Connector{db: db, config: ConnectorConfig{Type: dbType}}
+	t.Cleanup(func() { c.Close() })
+	return c, recorder
+}
+
+func TestGetAbilitiesByGroupSQL(t *testing.T) {
+	tests := []struct {
+		dbType ConnectorType
+		want   string
+	}{
+		{ConnectorTypeMySQL, "SELECT * FROM `abilities` WHERE `group` = 'vip'"},
+		{ConnectorTypePostgres, `SELECT * FROM "abilities" WHERE "group" = 'vip'`},
+		{ConnectorTypeSQLite, "SELECT * FROM `abilities` WHERE `group` = \"vip\""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dbType), func(t *testing.T) {
+			c, recorder := newDryRunConnector(t, tt.dbType)
+
+			if _, err := c.GetAbilitiesByGroup("vip"); err != nil {
+				t.Fatal(err)
+			}
+			if len(recorder.statements) != 1 || recorder.statements[0] != tt.want {
+				t.Errorf("statements %q, want %q", recorder.statements, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package newapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
+// channelKey is one key of a channel.
+type channelKey struct {
+	key string
+	// index is the position of the key in New API's key list, which keys
+	// the per-key status in ChannelInfo. Blank lines are skipped but keep
+	// their position, so it may differ from the position among the keys
+	// exported.
+	index int
+}
+
+// channelKeys splits the channel key into one key per provider. Vertex AI
+// keys are service account JSON documents that may span several lines;
+// multi-key Vertex AI channels store them as a JSON array.
+func channelKeys(ch Channel) []channelKey {
+	key := strings.TrimSpace(ch.Key)
+	if ChannelType(ch.Type) != ChannelTypeVertexAI {
+		return parseKeys(ch.Key)
+	}
+
+	if strings.HasPrefix(key, "[") {
+		var accounts []json.RawMessage
+		if err := json.Unmarshal([]byte(key), &accounts); err == nil && len(accounts) > 0 {
+			keys := make([]channelKey, len(accounts))
+			for i, account := range accounts {
+				keys[i] = channelKey{key: string(account), index: i}
+			}
+			return keys
+		}
+	}
+	if source.IsServiceAccountKey(key) && json.Valid([]byte(key)) {
+		return []channelKey{{key: key}}
+	}
+	return parseKeys(ch.Key)
+}
+
+// parseKeys parses the key field which may contain multiple keys separated
+// by newlines. Keys are indexed like New API does: by line, after removing
+// leading and trailing newlines.
+func parseKeys(key string) []channelKey {
+	if key == "" {
+		return nil
+	}
+
+	var keys []channelKey
+	for i, line := range strings.Split(strings.Trim(key, "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, channelKey{key: line, index: i})
+		}
+	}
+
+	if len(keys) == 0 {
+		return []channelKey{{key: key}}
+	}
+
+	return keys
+}
+
+// channelOptions decodes the provider options New API stores in the
+// channel's other and settings fields, and splits an Azure deployment off
+// the base URL. Values that cannot be parsed are left unset with a warning.
+func (e *Exporter) channelOptions(ch Channel, baseURL string) (string, schema.ProviderOptions) {
+	var options schema.ProviderOptions
+
+	switch ChannelType(ch.Type) {
+	case ChannelTypeAzure:
+		baseURL, options.Deployment = source.AzureDeployment(baseURL)
+		options.APIVersion = strings.TrimSpace(ch.Other)
+		if options.APIVersion == "" && strings.TrimSpace(ch.OtherSettings) != "" {
+			var settings struct {
+				APIVersion string `json:"api_version"`
+			}
+			if err := json.Unmarshal([]byte(ch.OtherSettings), &settings); err != nil {
+				e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "settings", ch.OtherSettings, fmt.Sprintf(
+					"Channel '%s' (ID=%d) has invalid settings JSON, Azure API version not migrated: %v",
+					ch.Name, ch.ID, err,
+				))
+			}
+			options.APIVersion = settings.APIVersion
+		}
+
+	case ChannelTypeVertexAI:
+		region, models, err := source.VertexRegions(ch.Other)
+		if err != nil {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "other", ch.Other, fmt.Sprintf(
+				"Channel '%s' (ID=%d) has an invalid Vertex AI region, not migrated: %v",
+				ch.Name, ch.ID, err,
+			))
+		}
+		options.Region, options.ModelRegions = region, models
+
+	case ChannelTypeCloudflare:
+		options.AccountID = strings.TrimSpace(ch.Other)
+		if options.AccountID == "" {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "other", nil, fmt.Sprintf(
+				"Channel '%s' (ID=%d) has no Cloudflare account ID",
+				ch.Name, ch.ID,
+			))
+		}
+	}
+
+	return baseURL, options
+}
+
+// keyCredentials decodes one channel key into the provider API key and
+// options, starting from the channel options. Keys that cannot be parsed
+// are exported as is with a warning; the key is never part of the warning.
+func (e *Exporter) keyCredentials(ch Channel, key string, index int, options schema.ProviderOptions) (string, *schema.ProviderOptions) {
+	switch ChannelType(ch.Type) {
+	case ChannelTypeAws:
+		accessKeyID, secret, region, err := source.SplitAWSKey(key)
+		if err != nil {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "key", nil, fmt.Sprintf(
+				"Channel '%s' (ID=%d) key %d is not a valid AWS key, exported as is: %v",
+				ch.Name, ch.ID, index+1, err,
+			))
+			break
+		}
+		key = secret
+		options.Region = region
+		options.AccessKeyID = accessKeyID
+		options.CredentialType = schema.CredentialAccessKey
+		if accessKeyID == "" {
+			options.CredentialType = schema.CredentialAPIKey
+		}
+
+	case ChannelTypeVertexAI:
+		if !source.IsServiceAccountKey(key) {
+			options.CredentialType = schema.CredentialAPIKey
+			break
+		}
+		options.CredentialType = schema.CredentialServiceAccount
+		project, err := source.ServiceAccountProject(key)
+		if err != nil {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "key", nil, fmt.Sprintf(
+				"Channel '%s' (ID=%d) key %d: %v, project not migrated",
+				ch.Name, ch.ID, index+1, err,
+			))
+		}
+		options.Project = project
+	}
+
+	return key, source.NonEmptyOptions(options)
+}
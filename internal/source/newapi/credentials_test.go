@@ -0,0 +1,151 @@
+package newapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// newTestExporter returns an exporter without a database that collects
+// warnings in the returned result.
+func newTestExporter() (*Exporter, *schema.ExportResult) {
+	result := schema.NewExportResult()
+	e := NewExporter(nil, ExporterConfig{})
+	e.sink = result
+	return e, result
+}
+
+func TestChannelKeys(t *testing.T) {
+	account1 := "{\n  \"type\": \"service_account\",\n  \"project_id\": \"p1\"\n}"
+	account2 := `{"type":"service_account","project_id":"p2"}`
+
+	tests := []struct {
+		name string
+		typ  ChannelType
+		key  string
+		want []channelKey
+	}{
+		{name: "single key", typ: ChannelTypeOpenAI, key: "sk-1", want: []channelKey{{"sk-1", 0}}},
+		{name: "multi-key", typ: ChannelTypeOpenAI, key: "sk-1\nsk-2\n", want: []channelKey{{"sk-1", 0}, {"sk-2", 1}}},
+		{
+			// Blank lines keep their index, like in New API
+			name: "blank lines skipped",
+			typ:  ChannelTypeOpenAI,
+			key:  "\nsk-1\n\n  sk-2  \r\n \nsk-3\n\n",
+			want: []channelKey{{"sk-1", 0}, {"sk-2", 2}, {"sk-3", 4}},
+		},
+		{name: "empty", typ: ChannelTypeOpenAI, key: "", want: nil},
+		{name: "vertex api key", typ: ChannelTypeVertexAI, key: "AIza-1", want: []channelKey{{"AIza-1", 0}}},
+		{name: "vertex api keys", typ: ChannelTypeVertexAI, key: "AIza-1\n\nAIza-2", want: []channelKey{{"AIza-1", 0}, {"AIza-2", 2}}},
+		{name: "vertex multi-line service account", typ: ChannelTypeVertexAI, key: account1, want: []channelKey{{account1, 0}}},
+		{
+			name: "vertex service account array",
+			typ:  ChannelTypeVertexAI,
+			key:  "[\n" + account1 + ",\n" + account2 + "\n]",
+			want: []channelKey{{account1, 0}, {account2, 1}},
+		},
+		{
+			// Not an array of accounts: split by lines like other keys
+			name: "vertex invalid array",
+			typ:  ChannelTypeVertexAI,
+			key:  "[\n" + account2 + ",\n",
+			want: []channelKey{{"[", 0}, {account2 + ",", 1}},
+		},
+		{
+			// Other channel types do not store JSON arrays
+			name: "openai array",
+			typ:  ChannelTypeOpenAI,
+			key:  "[\"sk-1\",\n\"sk-2\"]",
+			want: []channelKey{{`["sk-1",`, 0}, {`"sk-2"]`, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := channelKeys(Channel{Type: int(tt.typ), Key: tt.key})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("channelKeys = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     ChannelType
+		key     string
+		options schema.ProviderOptions // Channel options
+		apiKey  string
+		want    *schema.ProviderOptions
+		warning bool
+	}{
+		{name: "plain key", typ: ChannelTypeOpenAI, key: "sk-1", apiKey: "sk-1"},
+		{
+			name:   "aws access key",
+			typ:    ChannelTypeAws,
+			key:    "AKID|S3CR3T|us-east-1",
+			apiKey: "S3CR3T",
+			want:   &schema.ProviderOptions{CredentialType: schema.CredentialAccessKey, AccessKeyID: "AKID", Region: "us-east-1"},
+		},
+		{
+			name:   "aws bedrock api key",
+			typ:    ChannelTypeAws,
+			key:    "S3CR3T|us-west-2",
+			apiKey: "S3CR3T",
+			want:   &schema.ProviderOptions{CredentialType: schema.CredentialAPIKey, Region: "us-west-2"},
+		},
+		{name: "aws key missing region", typ: ChannelTypeAws, key: "AKID|S3CR3T|", apiKey: "AKID|S3CR3T|", warning: true},
+		{name: "aws key with extra fields", typ: ChannelTypeAws, key: "AKID|S3CR3T|us-east-1|x", apiKey: "AKID|S3CR3T|us-east-1|x", warning: true},
+		{
+			name:    "vertex api key keeps channel region",
+			typ:     ChannelTypeVertexAI,
+			key:     "AIza-S3CR3T",
+			options: schema.ProviderOptions{Region: "us-central1"},
+			apiKey:  "AIza-S3CR3T",
+			want:    &schema.ProviderOptions{CredentialType: schema.CredentialAPIKey, Region: "us-central1"},
+		},
+		{
+			name:   "vertex service account",
+			typ:    ChannelTypeVertexAI,
+			key:    `{"project_id":"p1","private_key":"S3CR3T"}`,
+			apiKey: `{"project_id":"p1","private_key":"S3CR3T"}`,
+			want:   &schema.ProviderOptions{CredentialType: schema.CredentialServiceAccount, Project: "p1"},
+		},
+		{
+			name:    "vertex service account without project",
+			typ:     ChannelTypeVertexAI,
+			key:     `{"private_key":"S3CR3T"}`,
+			apiKey:  `{"private_key":"S3CR3T"}`,
+			want:    &schema.ProviderOptions{CredentialType: schema.CredentialServiceAccount},
+			warning: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, result := newTestExporter()
+			ch := Channel{ID: 7, Name: "ch", Type: int(tt.typ)}
+
+			apiKey, options := e.keyCredentials(ch, tt.key, 1, tt.options)
+			if apiKey != tt.apiKey {
+				t.Errorf("API key %q, want %q", apiKey, tt.apiKey)
+			}
+			if !reflect.DeepEqual(options, tt.want) {
+				t.Errorf("options %+v, want %+v", options, tt.want)
+			}
+
+			if got := len(result.WarningDetails) > 0; got != tt.warning {
+				t.Fatalf("warnings %+v, want warning %v", result.WarningDetails, tt.warning)
+			}
+			for _, w := range result.WarningDetails {
+				if w.Code != schema.WarnInvalidCredentials || w.OriginalID != 7 || w.Field != "key" || len(w.Value) != 0 {
+					t.Errorf("warning %+v, want %s about the key without its value", w, schema.WarnInvalidCredentials)
+				}
+				if strings.Contains(w.Message, "S3CR3T") {
+					t.Errorf("warning message %q contains the key", w.Message)
+				}
+			}
+		})
+	}
+}
@@ -5,23 +5,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
 )
 
 // ExporterConfig holds configuration for the exporter.
 type ExporterConfig struct {
-	IncludeTokens    bool // Whether to include tokens in export
-	IncludeAbilities bool // Whether to include abilities (bindings)
-	Verbose          bool // Enable verbose logging
+	IncludeTokens         bool    // Whether to include tokens in export
+	IncludeAbilities      bool    // Whether to include abilities (bindings)
+	IncludeRedemptions    bool    // Whether to include redemption codes
+	IncludeAdministrators bool    // Whether to include admin and root users
+	BatchSize             int     // Rows read per query (0 = DefaultBatchSize)
+	Verbose               bool    // Enable verbose logging
+	Backup                string  // Policy for _original backups ("" = source.DefaultBackup)
+	QuotaPerUnit          float64 // Quota units per balance unit (0 = source.DefaultQuotaPerUnit)
+	MaxChildKeys          int     // Child keys allowed per master (0 = unset)
+	GlobalQPS             int     // QPS limit per master (0 = unset)
+
+	TypeMapping TypeMapping // Channel type mapping (zero value = built-in)
+
+	Since    time.Time        // Only export rows changed at or after Since (zero = full export)
+	Baseline *source.Baseline // Previous export, for detecting hard deletes (optional)
 }
 
+// DefaultBatchSize is the number of rows read per query when streaming tables.
+const DefaultBatchSize = 1000
+
 // DefaultExporterConfig returns default configuration.
 func DefaultExporterConfig() ExporterConfig {
 	return ExporterConfig{
 		IncludeTokens:    true,
 		IncludeAbilities: false,
+		BatchSize:        DefaultBatchSize,
 		Verbose:          false,
+		Backup:           source.DefaultBackup,
+		QuotaPerUnit:     source.DefaultQuotaPerUnit,
+		MaxChildKeys:     source.DefaultMaxChildKeys,
+		GlobalQPS:        source.DefaultGlobalQPS,
 	}
 }
 
@@ -29,74 +51,150 @@ func DefaultExporterConfig() ExporterConfig {
 type Exporter struct {
 	connector *Connector
 	config    ExporterConfig
-	result    *schema.ExportResult
+	sink      schema.Sink
+
+	channelNames source.ChannelNames // Number of channels using each name
 }
 
 // NewExporter creates a new exporter instance.
 func NewExporter(connector *Connector, config ExporterConfig) *Exporter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.Backup == "" {
+		config.Backup = source.DefaultBackup
+	}
+	if config.QuotaPerUnit <= 0 {
+		config.QuotaPerUnit = source.DefaultQuotaPerUnit
+	}
 	return &Exporter{
 		connector: connector,
 		config:    config,
-		result:    schema.NewExportResult(),
 	}
 }
 
-// Export performs the full export process.
+// Export performs the full export process and collects the result in memory.
 func (e *Exporter) Export() (*schema.ExportResult, error) {
+	result := schema.NewExportResult()
+	if err := e.ExportTo(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExportTo performs the full export process, sending entities to sink as
+// they are read. Tables are read in batches, so memory use stays bounded
+// when sink is a *schema.StreamWriter.
+func (e *Exporter) ExportTo(sink schema.Sink) error {
+	e.sink = sink
+
 	// Export channels -> providers
 	if err := e.exportChannels(); err != nil {
-		return nil, fmt.Errorf("failed to export channels: %w", err)
+		return fmt.Errorf("failed to export channels: %w", err)
 	}
 
 	// Export users and tokens -> masters and keys
 	if e.config.IncludeTokens {
 		if err := e.exportUsersAndTokens(); err != nil {
-			return nil, fmt.Errorf("failed to export users/tokens: %w", err)
+			return fmt.Errorf("failed to export users/tokens: %w", err)
 		}
 	}
 
 	// Export abilities -> bindings (optional)
-	if e.config.IncludeAbilities {
+	if e.config.IncludeAbilities && e.hasTable(&Ability{}, "bindings and failover groups") {
 		if err := e.exportAbilities(); err != nil {
-			return nil, fmt.Errorf("failed to export abilities: %w", err)
+			return fmt.Errorf("failed to export abilities: %w", err)
 		}
 	}
 
-	return e.result, nil
+	// Export redemption codes (optional)
+	if e.config.IncludeRedemptions && e.hasTable(&Redemption{}, "redemption codes") {
+		if err := e.exportRedemptions(); err != nil {
+			return fmt.Errorf("failed to export redemptions: %w", err)
+		}
+	}
+
+	// Export admin and root users -> administrators (optional)
+	if e.config.IncludeAdministrators {
+		if err := e.exportAdministrators(); err != nil {
+			return fmt.Errorf("failed to export administrators: %w", err)
+		}
+	}
+
+	// Export deleted rows -> tombstones (incremental exports only)
+	if e.incremental() {
+		if err := e.exportTombstones(); err != nil {
+			return fmt.Errorf("failed to export tombstones: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// hasTable reports whether the source database has the table of model.
+// Databases of older releases lack some tables; the section read from a
+// missing table is skipped with a warning.
+func (e *Exporter) hasTable(model tabler, section string) bool {
+	table := model.TableName()
+	if e.connector.HasTable(table) {
+		return true
+	}
+	e.sink.AddWarning(schema.Warning{
+		Code:     schema.WarnMissingTable,
+		Severity: schema.SeverityWarning,
+		Field:    table,
+		Message:  fmt.Sprintf("Table '%s' does not exist in the source database, %s were not exported", table, section),
+	})
+	return false
 }
 
-// exportChannels exports all channels as providers.
+// incremental reports whether only changes since config.Since are exported.
+func (e *Exporter) incremental() bool {
+	return !e.config.Since.IsZero()
+}
+
+// exportChannels exports all channels as providers. Incremental exports
+// only include channels created or tested since config.Since.
 func (e *Exporter) exportChannels() error {
-	channels, err := e.connector.GetAllChannels()
+	// Names are counted over all channels, so incremental exports rename
+	// the same channels as full ones
+	names, err := e.connector.GetChannelNameCounts()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to count channel names: %w", err)
 	}
+	e.channelNames = names
 
-	for _, ch := range channels {
-		providers := e.channelToProviders(ch)
-		for _, p := range providers {
-			e.result.AddProvider(p)
+	fn := func(channels []Channel) error {
+		for _, ch := range channels {
+			providers := e.channelToProviders(ch)
+			for _, p := range providers {
+				e.sink.AddProvider(p)
+			}
 		}
+		return nil
 	}
 
-	return nil
+	if e.incremental() {
+		return e.connector.EachChannelBatchSince(e.config.Since.Unix(), e.config.BatchSize, fn)
+	}
+	return e.connector.EachChannelBatch(e.config.BatchSize, fn)
 }
 
 // channelToProviders converts a New API channel to one or more EZ-API providers.
 // Multi-key channels are split into multiple providers.
 func (e *Exporter) channelToProviders(ch Channel) []schema.Provider {
 	// Parse keys (newline separated for multi-key)
-	keys := parseKeys(ch.Key)
+	keys := channelKeys(ch)
 	isMultiKey := len(keys) > 1
 
 	// Parse groups (comma separated for multi-group)
-	groups := parseGroups(ch.Group)
+	groups := source.ParseGroups(ch.Group)
 	primaryGroup := groups[0]
 
 	// Map channel type to provider type
-	providerType, typeOK := MapChannelType(ch.Type)
+	providerType, typeOK := e.config.TypeMapping.ProviderType(ChannelType(ch.Type))
 	if !typeOK {
-		e.result.AddWarning(fmt.Sprintf(
+		e.warnChannel(ch, schema.WarnUnknownChannelType, schema.SeverityWarning, "type", ch.Type, fmt.Sprintf(
 			"Channel '%s' (ID=%d) has unknown type %d, mapped to 'custom'",
 			ch.Name, ch.ID, ch.Type,
 		))
@@ -110,19 +208,16 @@ func (e *Exporter) channelToProviders(ch Channel) []schema.Provider {
 	if ch.BaseURL != nil {
 		baseURL = *ch.BaseURL
 	}
-
-	// Weight
-	weight := 1
-	priority := 0
-	if ch.Weight != nil && *ch.Weight > 0 {
-		weight = int(*ch.Weight)
-	} else if ch.Priority != nil && *ch.Priority > 0 {
-		weight = int(*ch.Priority)
-	}
-	if ch.Priority != nil && *ch.Priority > 0 {
-		priority = int(*ch.Priority)
+	if baseURL == "" {
+		baseURL = e.config.TypeMapping.DefaultBaseURL(ChannelType(ch.Type))
 	}
 
+	// Settings packed into other/settings (Azure, Vertex AI, Cloudflare)
+	baseURL, options := e.channelOptions(ch, baseURL)
+
+	// Weight and priority tier
+	weight, priority := source.ChannelWeight(ch.Weight, ch.Priority)
+
 	// Auto ban
 	autoBan := true
 	if ch.AutoBan != nil {
@@ -130,7 +225,10 @@ func (e *Exporter) channelToProviders(ch Channel) []schema.Provider {
 	}
 
 	// Parse models
-	models := parseModels(ch.Models)
+	models := source.ParseModels(ch.Models)
+
+	// Translate model mapping into aliases, following chained mappings
+	aliases := source.ModelAliases(e.sink, sourceChannel(ch), ch.ModelMapping, true)
 
 	// Create original backup
 	original := e.createOriginalBackup(ch)
@@ -139,19 +237,22 @@ func (e *Exporter) channelToProviders(ch Channel) []schema.Provider {
 	e.checkUnmappableFields(ch)
 
 	// Create providers for each key
+	baseName := e.channelNames.ProviderName(e.sink, sourceChannel(ch), len(keys))
 	var providers []schema.Provider
 	for i, key := range keys {
-		name := ch.Name
+		name := baseName
 		if isMultiKey && i > 0 {
-			name = fmt.Sprintf("%s-%d", ch.Name, i+1)
+			name = fmt.Sprintf("%s-%d", baseName, i+1)
 		}
 
+		apiKey, keyOptions := e.keyCredentials(ch, key.key, key.index, options)
+
 		p := schema.Provider{
 			OriginalID:   ch.ID,
 			Name:         name,
 			Type:         providerType,
 			BaseURL:      baseURL,
-			APIKey:       key,
+			APIKey:       apiKey,
 			Models:       models,
 			PrimaryGroup: primaryGroup,
 			AllGroups:    groups,
@@ -159,121 +260,193 @@ func (e *Exporter) channelToProviders(ch Channel) []schema.Provider {
 			Priority:     priority,
 			Status:       status,
 			AutoBan:      autoBan,
+			ModelAliases: aliases,
+			Options:      keyOptions,
 			IsMultiKey:   isMultiKey,
-			Original:     original,
+		}
+
+		// Split providers share the channel backup; only the first carries it
+		if i == 0 {
+			p.Original = original
 		}
 
 		if isMultiKey {
 			p.MultiKeyIndex = i + 1
+		}
+		if isMultiKey || baseName != ch.Name {
 			p.OriginalName = ch.Name
 		}
 
+		e.applyKeyStatus(&p, ch.ChannelInfo, key.index)
+
 		providers = append(providers, p)
 	}
 
 	return providers
 }
 
-// checkUnmappableFields checks for fields that cannot be mapped and adds warnings.
-func (e *Exporter) checkUnmappableFields(ch Channel) {
-	if ch.Priority != nil && *ch.Priority != 0 {
-		e.result.AddWarning(fmt.Sprintf(
-			"Channel '%s' (ID=%d) has priority=%d which is not supported in EZ-API",
-			ch.Name, ch.ID, *ch.Priority,
-		))
+// applyKeyStatus applies the per-key status that New API tracks for
+// multi-key channels in ChannelInfo, keyed by the 0-based key index (see
+// channelKey).
+// A disabled key stays disabled even when the channel itself is enabled;
+// a disabled channel keeps all of its keys disabled.
+func (e *Exporter) applyKeyStatus(p *schema.Provider, info *ChannelInfo, index int) {
+	if info == nil {
+		return
 	}
 
-	if ch.ModelMapping != nil && *ch.ModelMapping != "" {
-		e.result.AddWarning(fmt.Sprintf(
-			"Channel '%s' (ID=%d) has model_mapping which is not migrated. Use EZ-API Binding instead.",
-			ch.Name, ch.ID,
-		))
+	if keyStatus, ok := info.MultiKeyStatusList[index]; ok && !ChannelStatus(keyStatus).IsActive() {
+		p.Status = MapChannelStatus(keyStatus)
 	}
+	if p.Status == "active" {
+		return
+	}
+
+	p.DisabledReason = info.MultiKeyDisabledReason[index]
+	if ts := info.MultiKeyDisabledTime[index]; ts > 0 {
+		p.DisabledAt = TimestampToTime(ts)
+	}
+}
+
+// sourceChannel identifies a channel in warnings.
+func sourceChannel(ch Channel) source.Channel {
+	return source.Channel{ID: ch.ID, Name: ch.Name}
+}
+
+// warnChannel records a warning about a field of a channel, with the
+// field's original value.
+func (e *Exporter) warnChannel(ch Channel, code, severity, field string, value interface{}, msg string) {
+	sourceChannel(ch).Warn(e.sink, code, severity, field, value, msg)
+}
+
+// checkUnmappableFields checks for fields that cannot be mapped and adds warnings.
+func (e *Exporter) checkUnmappableFields(ch Channel) {
+	source.CheckRouting(e.sink, sourceChannel(ch), ch.Group, ch.Priority, e.config.IncludeAbilities)
 
 	if ch.StatusCodeMapping != nil && *ch.StatusCodeMapping != "" {
-		e.result.AddWarning(fmt.Sprintf(
+		e.warnChannel(ch, schema.WarnUnmappedStatusCodeMap, schema.SeverityWarning, "status_code_mapping", *ch.StatusCodeMapping, fmt.Sprintf(
 			"Channel '%s' (ID=%d) has status_code_mapping which is not supported in EZ-API",
 			ch.Name, ch.ID,
 		))
 	}
 
 	if ch.Setting != nil && *ch.Setting != "" {
-		e.result.AddWarning(fmt.Sprintf(
+		e.warnChannel(ch, schema.WarnUnmappedSetting, schema.SeverityWarning, "setting", *ch.Setting, fmt.Sprintf(
 			"Channel '%s' (ID=%d) has custom settings which are not migrated",
 			ch.Name, ch.ID,
 		))
 	}
 
 	if ch.ParamOverride != nil && *ch.ParamOverride != "" {
-		e.result.AddWarning(fmt.Sprintf(
+		e.warnChannel(ch, schema.WarnUnmappedParamOverride, schema.SeverityWarning, "param_override", *ch.ParamOverride, fmt.Sprintf(
 			"Channel '%s' (ID=%d) has param_override which is not supported in EZ-API",
 			ch.Name, ch.ID,
 		))
 	}
 
 	if ch.HeaderOverride != nil && *ch.HeaderOverride != "" {
-		e.result.AddWarning(fmt.Sprintf(
+		e.warnChannel(ch, schema.WarnUnmappedHeaderOverride, schema.SeverityWarning, "header_override", *ch.HeaderOverride, fmt.Sprintf(
 			"Channel '%s' (ID=%d) has header_override which is not supported in EZ-API",
 			ch.Name, ch.ID,
 		))
 	}
+}
 
-	// Multi-group warning
-	groups := parseGroups(ch.Group)
-	if len(groups) > 1 {
-		e.result.AddWarning(fmt.Sprintf(
-			"Channel '%s' (ID=%d) belongs to multiple groups %v. Only '%s' is used as primary group. Consider creating Bindings for other groups.",
-			ch.Name, ch.ID, groups, groups[0],
-		))
-	}
+// channelUnmappableFields are the channel fields EZ-API has no equivalent
+// for, kept by the unmappable backup policy.
+var channelUnmappableFields = []string{
+	"model_mapping", "setting", "param_override", "header_override", "status_code_mapping", "other_info",
 }
 
-// createOriginalBackup creates a JSON backup of original channel data.
+// createOriginalBackup creates a JSON backup of original channel data
+// according to the backup policy.
 func (e *Exporter) createOriginalBackup(ch Channel) json.RawMessage {
-	data, err := json.Marshal(ch)
-	if err != nil {
-		return nil
-	}
-	return data
+	return source.Backup(e.config.Backup, ch, schema.BackupSecretFields, channelUnmappableFields)
 }
 
 // exportUsersAndTokens exports users and tokens as masters and keys.
+// Users are read first to emit all masters, then tokens are scanned once in
+// (user_id, id) order and matched to their master in memory, so keys come
+// out grouped by user in the same order as the masters.
+//
+// Incremental exports only include tokens created or accessed since
+// config.Since, together with the masters they belong to.
 func (e *Exporter) exportUsersAndTokens() error {
-	// Get all users with tokens
-	users, err := e.connector.GetUsersWithTokens()
-	if err != nil {
-		return fmt.Errorf("failed to get users: %w", err)
+	eachUserBatch := e.connector.EachUserWithTokensBatch
+	eachTokenBatch := e.connector.EachTokenBatchByUser
+	if e.incremental() {
+		since := e.config.Since.Unix()
+		eachUserBatch = func(batchSize int, fn func([]User) error) error {
+			return e.connector.EachUserWithTokensBatchSince(since, batchSize, fn)
+		}
+		eachTokenBatch = func(batchSize int, fn func([]Token) error) error {
+			return e.connector.EachTokenBatchByUserSince(since, batchSize, fn)
+		}
 	}
 
-	// Create a map to track masters
-	masterMap := make(map[int]string) // user_id -> master_name
-
-	for _, user := range users {
-		// Create master from user
-		master := e.userToMaster(user)
-		e.result.AddMaster(master)
-		masterMap[user.ID] = master.Name
-
-		// Get tokens for this user
-		tokens, err := e.connector.GetTokensByUserID(user.ID)
-		if err != nil {
-			e.result.AddWarning(fmt.Sprintf(
-				"Failed to get tokens for user '%s' (ID=%d): %v",
-				user.Username, user.ID, err,
-			))
-			continue
+	masters := source.NewMasters(e.sink)
+	err := eachUserBatch(e.config.BatchSize, func(users []User) error {
+		for _, user := range users {
+			masters.Add(e.userToMaster(user))
 		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get users: %w", err)
+	}
 
-		// Convert tokens to keys
+	err = eachTokenBatch(e.config.BatchSize, func(tokens []Token) error {
 		for _, token := range tokens {
-			key := e.tokenToKey(token, master.Name)
-			e.result.AddKey(key)
+			if master, ok := masters.TokenMaster(token.UserID); ok {
+				e.sink.AddKey(e.tokenToKey(token, master.Name))
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get tokens: %w", err)
 	}
 
+	masters.Warn()
 	return nil
 }
 
+// exportAdministrators exports admin and root users as administrators.
+// There are few of them and their rows carry no change time, so they are
+// always exported in full.
+func (e *Exporter) exportAdministrators() error {
+	users, err := e.connector.GetAdminUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		e.sink.AddAdministrator(userToAdministrator(user))
+	}
+	return nil
+}
+
+// userToAdministrator converts a New API admin user to an EZ-API
+// administrator. The access token itself is not exported.
+func userToAdministrator(user User) schema.Administrator {
+	return schema.Administrator{
+		Name:           user.Username,
+		DisplayName:    user.DisplayName,
+		Email:          user.Email,
+		Role:           MapUserRole(user.Role),
+		Status:         MapUserStatus(user.Status),
+		HasAccessToken: user.AccessToken != nil && *user.AccessToken != "",
+		SourceUserID:   user.ID,
+		Identities: source.Identities(
+			schema.Identity{Provider: "github", Subject: user.GitHubID},
+			schema.Identity{Provider: "discord", Subject: user.DiscordID},
+			schema.Identity{Provider: "oidc", Subject: user.OidcID},
+			schema.Identity{Provider: "wechat", Subject: user.WeChatID},
+			schema.Identity{Provider: "telegram", Subject: user.TelegramID},
+			schema.Identity{Provider: "linuxdo", Subject: user.LinuxDOID},
+		),
+	}
+}
+
 // userToMaster converts a New API user to an EZ-API master.
 func (e *Exporter) userToMaster(user User) schema.Master {
 	return schema.Master{
@@ -281,11 +454,22 @@ func (e *Exporter) userToMaster(user User) schema.Master {
 		Group:            user.Group,
 		Namespaces:       []string{user.Group},
 		DefaultNamespace: user.Group,
-		MaxChildKeys:     10, // Default value
-		GlobalQPS:        3,  // Default value
+		MaxChildKeys:     e.config.MaxChildKeys,
+		GlobalQPS:        e.config.GlobalQPS,
 		Status:           MapUserStatus(user.Status),
+		Role:             MapUserRole(user.Role),
+		Quota:            int64(user.Quota),
+		QuotaUsed:        int64(user.UsedQuota),
+		Balance:          source.Balance(int64(user.Quota), e.config.QuotaPerUnit),
+		BalanceUsed:      source.Balance(int64(user.UsedQuota), e.config.QuotaPerUnit),
+		RequestCount:     int64(user.RequestCount),
+		AffCode:          user.AffCode,
+		AffCount:         user.AffCount,
+		AffQuota:         int64(user.AffQuota),
+		AffHistoryQuota:  int64(user.AffHistoryQuota),
 		SourceUserID:     user.ID,
 		SourceEmail:      user.Email,
+		InviterUserID:    user.InviterID,
 	}
 }
 
@@ -306,7 +490,7 @@ func (e *Exporter) tokenToKey(token Token, masterRef string) schema.Key {
 
 	// Parse model limits
 	if token.ModelLimitsEnabled && token.ModelLimits != "" {
-		key.ModelLimits = parseModels(token.ModelLimits)
+		key.ModelLimits = source.ParseModels(token.ModelLimits)
 	}
 
 	// Parse expiration time
@@ -333,98 +517,121 @@ func (e *Exporter) tokenToKey(token Token, masterRef string) schema.Key {
 	return key
 }
 
-// exportAbilities exports abilities as bindings.
+// exportAbilities exports abilities as bindings and failover groups.
+// Abilities are bounded by channels x models x groups, so they are read at once.
 func (e *Exporter) exportAbilities() error {
 	abilities, err := e.connector.GetAllAbilities()
 	if err != nil {
 		return err
 	}
 
+	routes := make([]source.Ability, 0, len(abilities))
 	for _, ab := range abilities {
-		binding := e.abilityToBinding(ab)
-		e.result.AddBinding(binding)
+		routes = append(routes, abilityToSource(ab))
 	}
-
+	source.ExportAbilities(e.sink, routes)
 	return nil
 }
 
-// abilityToBinding converts a New API ability to an EZ-API binding.
-func (e *Exporter) abilityToBinding(ab Ability) schema.Binding {
-	status := "active"
-	if !ab.Enabled {
-		status = "disabled"
-	}
-
-	return schema.Binding{
-		Namespace:  ab.Group,
-		RouteGroup: ab.Group, // Use group as route_group
-		Model:      ab.Model,
-		Status:     status,
+// abilityToSource converts a New API ability to a weighted route.
+func abilityToSource(ab Ability) source.Ability {
+	var priority int64
+	if ab.Priority != nil {
+		priority = *ab.Priority
+	}
+	return source.Ability{
+		Route: source.Route{
+			Group:     ab.Group,
+			Model:     ab.Model,
+			ChannelID: ab.ChannelID,
+			Priority:  priority,
+			Weight:    int(ab.Weight),
+		},
+		Enabled: ab.Enabled,
 	}
 }
 
-// ============================================
-// Helper Functions
-// ============================================
-
-// parseKeys parses the key field which may contain multiple keys separated by newlines.
-func parseKeys(key string) []string {
-	if key == "" {
+// exportRedemptions exports redemption codes. Incremental exports only
+// include codes created or redeemed since config.Since.
+func (e *Exporter) exportRedemptions() error {
+	codes := source.NewRedemptions(e.sink)
+	fn := func(redemptions []Redemption) error {
+		for _, r := range redemptions {
+			codes.Add(redemptionToSchema(r))
+		}
 		return nil
 	}
 
-	lines := strings.Split(key, "\n")
-	var keys []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			keys = append(keys, line)
-		}
+	var err error
+	if e.incremental() {
+		err = e.connector.EachRedemptionBatchSince(e.config.Since.Unix(), e.config.BatchSize, fn)
+	} else {
+		err = e.connector.EachRedemptionBatch(e.config.BatchSize, fn)
 	}
-
-	if len(keys) == 0 {
-		return []string{key}
+	if err != nil {
+		return err
 	}
 
-	return keys
+	codes.Warn()
+	return nil
 }
 
-// parseGroups parses the group field which may contain multiple groups separated by commas.
-func parseGroups(group string) []string {
-	if group == "" {
-		return []string{"default"}
+// redemptionToSchema converts a New API redemption code.
+func redemptionToSchema(r Redemption) schema.Redemption {
+	return schema.Redemption{
+		OriginalID:     r.ID,
+		Name:           r.Name,
+		Code:           r.Key,
+		Quota:          int64(r.Quota),
+		Status:         MapRedemptionStatus(r.Status),
+		CreatedAt:      TimestampToTime(r.CreatedTime),
+		ExpiresAt:      TimestampToTime(r.ExpiredTime),
+		CreatorUserID:  r.UserID,
+		RedeemerUserID: r.UsedUserID,
+		RedeemedAt:     TimestampToTime(r.RedeemedTime),
 	}
+}
 
-	parts := strings.Split(group, ",")
-	var groups []string
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part != "" {
-			groups = append(groups, part)
-		}
+// exportTombstones exports channels, users and tokens deleted since
+// config.Since. Users and tokens are soft-deleted in New API, so they carry
+// their deletion time. Channels are hard-deleted and, like any other row
+// removed from the database, can only be found by comparing against the
+// baseline export.
+func (e *Exporter) exportTombstones() error {
+	tables := []source.DeletedRows{
+		{Kind: schema.TombstoneProvider, Table: "channel", LiveIDs: e.connector.GetChannelIDs},
 	}
-
-	if len(groups) == 0 {
-		return []string{"default"}
+	if e.config.IncludeTokens {
+		tables = append(tables,
+			source.DeletedRows{Kind: schema.TombstoneMaster, Table: "user", SoftDeleted: e.deletedUsers, LiveIDs: e.connector.GetUserIDs},
+			source.DeletedRows{Kind: schema.TombstoneKey, Table: "token", SoftDeleted: e.deletedTokens, LiveIDs: e.connector.GetTokenIDs},
+		)
 	}
-
-	return groups
+	return source.ExportTombstones(e.sink, e.config.Baseline, tables)
 }
 
-// parseModels parses the models field which contains comma-separated model names.
-func parseModels(models string) []string {
-	if models == "" {
-		return nil
+// deletedUsers returns the tombstones of users deleted since config.Since.
+func (e *Exporter) deletedUsers() ([]schema.Tombstone, error) {
+	users, err := e.connector.GetUsersDeletedSince(e.config.Since)
+	if err != nil {
+		return nil, err
 	}
-
-	parts := strings.Split(models, ",")
-	var result []string
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part != "" {
-			result = append(result, part)
-		}
+	var tombstones []schema.Tombstone
+	for _, user := range users {
+		tombstones = append(tombstones, source.SoftDeleteTombstone(schema.TombstoneMaster, user.ID, user.Username, user.DeletedAt.Time))
 	}
+	return tombstones, nil
+}
 
-	return result
+// deletedTokens returns the tombstones of tokens deleted since config.Since.
+func (e *Exporter) deletedTokens() ([]schema.Tombstone, error) {
+	tokens, err := e.connector.GetTokensDeletedSince(e.config.Since)
+	if err != nil {
+		return nil, err
+	}
+	var tombstones []schema.Tombstone
+	for _, token := range tokens {
+		tombstones = append(tombstones, source.SoftDeleteTombstone(schema.TombstoneKey, token.ID, "", token.DeletedAt.Time))
+	}
+	return tombstones, nil
 }
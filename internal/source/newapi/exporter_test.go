@@ -0,0 +1,291 @@
+package newapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
+func TestExportDuplicateChannelNames(t *testing.T) {
+	c := newTestConnector(t)
+	channels := []Channel{
+		{ID: 1, Name: "openai", Key: "sk-1", Status: 1, Models: "gpt-4o", Group: "default"},
+		{ID: 2, Name: "openai", Key: "sk-2", Status: 1, Models: "gpt-4o", Group: "default"},
+		{ID: 3, Name: "claude", Key: "sk-3\nsk-4\nsk-5", Status: 1, Models: "claude-3", Group: "default"},
+		{ID: 4, Name: "claude-2", Key: "sk-6", Status: 1, Models: "claude-3", Group: "default"},
+		{ID: 5, Name: "gemini", Key: "sk-7", Status: 1, Models: "gemini-pro", Group: "default"},
+	}
+	if err := c.GetDB().Create(&channels).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewExporter(c, ExporterConfig{}).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, p := range result.Data.Providers {
+		names = append(names, p.Name+"/"+p.OriginalName)
+	}
+	want := []string{"openai-1/openai", "openai-2/openai", "claude-3/claude", "claude-3-2/claude", "claude-3-3/claude", "claude-2/", "gemini/"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("providers (name/original name) %v, want %v", names, want)
+	}
+
+	var renamed []int
+	for _, w := range result.WarningDetails {
+		if w.Code == schema.WarnDuplicateChannelName {
+			renamed = append(renamed, w.OriginalID)
+		}
+	}
+	if fmt.Sprint(renamed) != "[1 2 3]" {
+		t.Errorf("%s warnings for channels %v, want [1 2 3]", schema.WarnDuplicateChannelName, renamed)
+	}
+	if violations := result.Validate(); len(violations) > 0 {
+		t.Errorf("export is invalid: %v", violations)
+	}
+}
+
+func TestExportAdministratorsOptIn(t *testing.T) {
+	c := newTestConnector(t)
+	users := []User{
+		{ID: 1, Username: "root", Email: "root@example.com", Status: 1, Role: int(RoleRootUser), Group: "default", AffCode: "a1"},
+		{ID: 2, Username: "alice", Status: 1, Role: int(RoleCommonUser), Group: "default", AffCode: "a2"},
+	}
+	if err := c.GetDB().Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewExporter(c, ExporterConfig{}).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(result.Data.Administrators); n != 0 {
+		t.Errorf("%d administrators exported by default, want none", n)
+	}
+
+	result, err = NewExporter(c, ExporterConfig{IncludeAdministrators: true}).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admins := result.Data.Administrators; len(admins) != 1 || admins[0].Name != "root" || admins[0].Email != "root@example.com" {
+		t.Errorf("administrators %+v, want root", admins)
+	}
+}
+
+func TestExportKeyStatusAfterBlankLine(t *testing.T) {
+	c := newTestConnector(t)
+	// New API keeps the position of blank lines: "sk-2" is key 2
+	ch := Channel{ID: 1, Name: "multi", Key: "sk-1\n\nsk-2\nsk-3", Status: 1, Models: "gpt-4o", Group: "default"}
+	if err := c.GetDB().Omit("channel_info").Create(&ch).Error; err != nil {
+		t.Fatal(err)
+	}
+	info := `{"is_multi_key":true,"multi_key_status_list":{"2":3},` +
+		`"multi_key_disabled_reason":{"2":"quota exceeded"},"multi_key_disabled_time":{"2":1700000000}}`
+	if err := c.GetDB().Exec("UPDATE channels SET channel_info = ? WHERE id = 1", info).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewExporter(c, ExporterConfig{}).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range result.Data.Providers {
+		got = append(got, fmt.Sprintf("%s/%s/%s/%q", p.Name, p.APIKey, p.Status, p.DisabledReason))
+	}
+	want := []string{
+		`multi/sk-1/active/""`,
+		`multi-2/sk-2/disabled/"quota exceeded"`,
+		`multi-3/sk-3/active/""`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("providers (name/key/status/reason)\n%v\nwant\n%v", got, want)
+	}
+	if p := result.Data.Providers[1]; p.DisabledAt == nil || p.DisabledAt.Unix() != 1700000000 {
+		t.Errorf("disabled at %v, want 1700000000", p.DisabledAt)
+	}
+}
+
+func TestExportMatchesSchema(t *testing.T) {
+	c := newTestConnector(t)
+	mapping := `{"gpt-4":"gpt-4o"}`
+	priority := int64(10)
+	db := c.GetDB()
+	seed := []interface{}{
+		&[]Channel{
+			{ID: 1, Name: "openai", Key: "sk-1\nsk-2", Status: 1, Models: "gpt-4o,gpt-4", Group: "default,vip", ModelMapping: &mapping, Priority: &priority},
+			{ID: 2, Name: "bedrock", Type: int(ChannelTypeAws), Key: "AKID|S3CR3T|us-east-1", Status: 2, Models: "claude-3", Group: "default"},
+		},
+		&[]User{
+			{ID: 1, Username: "root", Status: 1, Role: int(RoleRootUser), Group: "default", AffCode: "a1", GitHubID: "gh-1"},
+			{ID: 2, Username: "alice", Status: 2, Role: int(RoleCommonUser), Group: "default", AffCode: "a2", Quota: 750000, InviterID: 1},
+		},
+		&[]Token{
+			{ID: 1, UserID: 2, Key: "tok-1", Status: 1, Name: "t1", ExpiredTime: -1, ModelLimitsEnabled: true, ModelLimits: "gpt-4o"},
+			{ID: 2, UserID: 2, Key: "tok-2", Status: 3, Name: "t2", ExpiredTime: 1700000000, RemainQuota: 100},
+			{ID: 3, UserID: 9, Key: "tok-3", Status: 1, Name: "orphan"},
+		},
+		&[]Ability{
+			{Group: "default", Model: "gpt-4o", ChannelID: 1, Enabled: true, Priority: &priority},
+			{Group: "default", Model: "gpt-4o", ChannelID: 2, Enabled: false},
+		},
+		&[]Redemption{
+			{ID: 1, UserID: 1, Key: "code-1", Status: 3, Quota: 1000, CreatedTime: 1700000000, RedeemedTime: 1700000100, UsedUserID: 2},
+		},
+	}
+	for _, rows := range seed {
+		if err := db.Omit("channel_info").Create(rows).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := DefaultExporterConfig()
+	config.IncludeAbilities = true
+	config.IncludeRedemptions = true
+	config.IncludeAdministrators = true
+	result, err := NewExporter(c, config).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := result.GetSummary()
+	if summary.Providers != 3 || summary.Masters != 1 || summary.Keys != 2 || summary.FailoverGroups == 0 ||
+		summary.Redemptions != 1 || summary.Administrators != 1 || summary.Warnings == 0 {
+		t.Fatalf("export is missing sections: %+v", summary)
+	}
+
+	data, err := result.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if violations := schema.GenerateJSONSchema().ValidateJSON(doc); len(violations) > 0 {
+		t.Errorf("export does not match the schema: %v", violations)
+	}
+	if violations := result.Validate(); len(violations) > 0 {
+		t.Errorf("export is invalid: %v", violations)
+	}
+}
+
+func TestExportTombstones(t *testing.T) {
+	c := newTestConnector(t)
+	db := c.GetDB()
+	since := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	users := []User{
+		{ID: 1, Username: "alice", Status: 1, AffCode: "a1"},
+		{ID: 2, Username: "bob", Status: 1, AffCode: "a2"},
+		{ID: 3, Username: "carol", Status: 1, AffCode: "a3"},
+	}
+	tokens := []Token{
+		{ID: 10, UserID: 1, Key: "tok-10", Status: 1},
+		{ID: 11, UserID: 1, Key: "tok-11", Status: 1},
+		{ID: 12, UserID: 1, Key: "tok-12", Status: 1},
+	}
+	channels := []Channel{{ID: 1, Name: "openai", Key: "sk-1", Status: 1, Models: "gpt-4o", Group: "default"}}
+	for _, rows := range []interface{}{&users, &tokens, &channels} {
+		if err := db.Create(rows).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleted after since: bob and token 11; before since: carol and token 12
+	deleted := map[interface{}]time.Time{
+		&User{ID: 2}:   since.Add(time.Hour),
+		&User{ID: 3}:   since.Add(-time.Hour),
+		&Token{ID: 11}: since.Add(2 * time.Hour),
+		&Token{ID: 12}: since.Add(-time.Hour),
+	}
+	for row, at := range deleted {
+		if err := db.Model(row).Update("deleted_at", at).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tombstones := func(baseline *source.Baseline) []string {
+		t.Helper()
+		config := DefaultExporterConfig()
+		config.Since, config.Baseline = since, baseline
+		result, err := NewExporter(c, config).Export()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, ts := range result.Data.Tombstones {
+			at := "-"
+			if ts.DeletedAt != nil {
+				at = ts.DeletedAt.Format(time.RFC3339)
+			}
+			got = append(got, fmt.Sprintf("%s/%d/%s/%s", ts.Kind, ts.OriginalID, ts.Name, at))
+		}
+		return got
+	}
+
+	want := []string{"master/2/bob/2025-03-01T01:00:00Z", "key/11//2025-03-01T02:00:00Z"}
+	if got := tombstones(nil); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("tombstones\n%v\nwant\n%v", got, want)
+	}
+
+	// Rows of the previous export that are no longer live are reported too,
+	// without a deletion time unless they were soft-deleted since then
+	previous := schema.NewExportResult()
+	previous.Data.Providers = []schema.Provider{{OriginalID: 1, Name: "openai"}, {OriginalID: 9, Name: "gone-2", OriginalName: "gone"}}
+	previous.Data.Masters = []schema.Master{{SourceUserID: 1, Name: "alice"}, {SourceUserID: 3, Name: "carol"}, {SourceUserID: 4, Name: "dave"}}
+	previous.Data.Keys = []schema.Key{{OriginalID: 10}, {OriginalID: 12}, {OriginalID: 13}}
+	want = []string{
+		"provider/9/gone/-",
+		"master/2/bob/2025-03-01T01:00:00Z", "master/3/carol/-", "master/4/dave/-",
+		"key/11//2025-03-01T02:00:00Z", "key/12//-", "key/13//-",
+	}
+	if got := tombstones(source.NewBaseline(previous)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("tombstones with baseline\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestExportMasterQuota(t *testing.T) {
+	c := newTestConnector(t)
+	user := User{ID: 1, Username: "alice", Status: 1, AffCode: "a1", Quota: 1250000, UsedQuota: 1}
+	token := Token{ID: 1, UserID: 1, Key: "tok-1", Status: 1}
+	if err := c.GetDB().Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetDB().Create(&token).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                    string
+		config                  ExporterConfig
+		balance, balanceUsed    float64
+		maxChildKeys, globalQPS int
+	}{
+		{name: "defaults", config: DefaultExporterConfig(), balance: 2.5, balanceUsed: 0.000002, maxChildKeys: 10, globalQPS: 3},
+		{name: "unset ratio", config: ExporterConfig{IncludeTokens: true}, balance: 2.5, balanceUsed: 0.000002},
+		{name: "custom", config: ExporterConfig{IncludeTokens: true, QuotaPerUnit: 1, MaxChildKeys: 5, GlobalQPS: 1}, balance: 1250000, balanceUsed: 1, maxChildKeys: 5, globalQPS: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewExporter(c, tt.config).Export()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Data.Masters) != 1 {
+				t.Fatalf("masters %+v, want alice", result.Data.Masters)
+			}
+			m := result.Data.Masters[0]
+			if m.Quota != 1250000 || m.Balance != tt.balance || m.BalanceUsed != tt.balanceUsed || m.MaxChildKeys != tt.maxChildKeys || m.GlobalQPS != tt.globalQPS {
+				t.Errorf("quota %d, balance %v/%v, limits %d/%d, want 1250000, %v/%v, %d/%d",
+					m.Quota, m.Balance, m.BalanceUsed, m.MaxChildKeys, m.GlobalQPS, tt.balance, tt.balanceUsed, tt.maxChildKeys, tt.globalQPS)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Package newapi provides the source.Source implementation for New API.
+package newapi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
+	"github.com/EZ-Api/exporter/internal/source/database"
+	"gorm.io/gorm"
+)
+
+// SourceType is the value recorded in schema.Source.Type for New API exports.
+const SourceType = "newapi"
+
+func init() {
+	source.Register(SourceType, func() source.Source { return &Source{} })
+}
+
+// Source exports New API databases.
+type Source struct {
+	connector *Connector
+	types     TypeMapping
+}
+
+// Connect opens the New API database.
+func (s *Source) Connect(config database.Config) error {
+	connector, err := NewConnector(config)
+	if err != nil {
+		return err
+	}
+	if err := connector.Ping(); err != nil {
+		connector.Close()
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	if err := connector.DetectSchema(); err != nil {
+		connector.Close()
+		return fmt.Errorf("failed to detect database schema: %w", err)
+	}
+	s.connector = connector
+	return nil
+}
+
+// Close closes the New API database.
+func (s *Source) Close() error {
+	if s.connector == nil {
+		return nil
+	}
+	return s.connector.Close()
+}
+
+// DetectVersion returns the range of New API versions whose schema matches
+// the database (see Connector.SchemaVersion). New API does not record its
+// version in the database, so it is inferred from the columns.
+func (s *Source) DetectVersion() (string, error) {
+	return s.connector.SchemaVersion(), nil
+}
+
+// Stats returns entity counts.
+func (s *Source) Stats() (*source.Stats, error) {
+	stats, err := s.connector.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	return &source.Stats{
+		Channels:  stats.Channels,
+		Tokens:    stats.Tokens,
+		Users:     stats.Users,
+		Abilities: stats.Abilities,
+	}, nil
+}
+
+// ActiveStats returns counts of enabled channels, tokens and users.
+func (s *Source) ActiveStats() (*source.ActiveStats, error) {
+	c := s.connector
+	stats := &source.ActiveStats{}
+
+	if err := c.table(&Channel{}).Where("status = ?", ChannelStatusEnabled).Count(&stats.Channels).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active channels: %w", err)
+	}
+	if err := c.table(&Token{}).Where("status = ?", TokenStatusEnabled).Count(&stats.Tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active tokens: %w", err)
+	}
+	if err := c.table(&User{}).Where("status = ?", UserStatusEnabled).Count(&stats.Users).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active users: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SetTypeOverrides sets the channel type overrides used by Export (see
+// source.TypeMapSource).
+func (s *Source) SetTypeOverrides(overrides map[int]source.TypeOverride) error {
+	types, err := NewTypeMapping(overrides)
+	if err != nil {
+		return err
+	}
+	s.types = types
+	return nil
+}
+
+// Export sets the source information on sink and sends all entities to it.
+// With options.Snapshot, all tables are read inside one read-only
+// transaction and the snapshot time is recorded in the source information.
+func (s *Source) Export(options source.ExportOptions, sink schema.Sink) error {
+	version, err := s.DetectVersion()
+	if err != nil {
+		return fmt.Errorf("failed to detect version: %w", err)
+	}
+
+	info := schema.Source{
+		Type:       SourceType,
+		Version:    version,
+		ExportedAt: time.Now(),
+
+		MissingColumns: s.connector.MissingColumns(),
+	}
+
+	config := ExporterConfig{
+		IncludeTokens:         options.IncludeTokens,
+		IncludeAbilities:      options.IncludeAbilities,
+		IncludeRedemptions:    options.IncludeRedemptions,
+		IncludeAdministrators: options.IncludeAdministrators,
+		BatchSize:             options.BatchSize,
+		Verbose:               options.Verbose,
+		Since:                 options.Since,
+		Baseline:              options.Baseline,
+		Backup:                options.Backup,
+		QuotaPerUnit:          options.QuotaPerUnit,
+		MaxChildKeys:          options.MaxChildKeys,
+		GlobalQPS:             options.GlobalQPS,
+		TypeMapping:           s.types,
+	}
+	if config.Backup == "" {
+		config.Backup = source.DefaultBackup
+	}
+	if config.QuotaPerUnit <= 0 {
+		config.QuotaPerUnit = source.DefaultQuotaPerUnit
+	}
+	info.Backup = config.Backup
+	info.QuotaPerUnit = config.QuotaPerUnit
+	if !options.Since.IsZero() {
+		info.Delta = &schema.Delta{Since: options.Since.UTC()}
+	}
+
+	if !options.Snapshot {
+		sink.SetSource(info)
+		return NewExporter(s.connector, config).ExportTo(sink)
+	}
+
+	return database.Snapshot(s.connector.GetDB(), s.connector.config.Type, func(tx *gorm.DB, at time.Time) error {
+		info.SnapshotAt = &at
+		sink.SetSource(info)
+		return NewExporter(s.connector.withDB(tx), config).ExportTo(sink)
+	})
+}
@@ -89,6 +89,18 @@ const (
 	RedemptionStatusUsed     RedemptionStatus = 3
 )
 
+// ToEzAPIStatus converts New API redemption status to EZ-API status string.
+func (s RedemptionStatus) ToEzAPIStatus() string {
+	switch s {
+	case RedemptionStatusEnabled:
+		return "active"
+	case RedemptionStatusUsed:
+		return "used"
+	default:
+		return "disabled"
+	}
+}
+
 // UserRole represents user role enum in New API.
 type UserRole int
 
@@ -104,16 +116,40 @@ func (r UserRole) IsAdmin() bool {
 	return r >= RoleAdminUser
 }
 
+// ToEzAPIRole converts New API user role to EZ-API role string.
+func (r UserRole) ToEzAPIRole() string {
+	switch {
+	case r >= RoleRootUser:
+		return "root"
+	case r >= RoleAdminUser:
+		return "admin"
+	case r >= RoleCommonUser:
+		return "user"
+	default:
+		return "guest"
+	}
+}
+
 // MapUserStatus maps integer user status to EZ-API status string.
 func MapUserStatus(status int) string {
 	return UserStatus(status).ToEzAPIStatus()
 }
 
+// MapUserRole maps integer user role to EZ-API role string.
+func MapUserRole(role int) string {
+	return UserRole(role).ToEzAPIRole()
+}
+
 // MapTokenStatus maps integer token status to EZ-API status string.
 func MapTokenStatus(status int) string {
 	return TokenStatus(status).ToEzAPIStatus()
 }
 
+// MapRedemptionStatus maps integer redemption status to EZ-API status string.
+func MapRedemptionStatus(status int) string {
+	return RedemptionStatus(status).ToEzAPIStatus()
+}
+
 // MapChannelStatus maps integer channel status to EZ-API status string.
 func MapChannelStatus(status int) string {
 	return ChannelStatus(status).ToEzAPIStatus()
@@ -0,0 +1,175 @@
+// Package oneapi provides channel type enum mapping.
+// The One API enum diverges from New API after type 25, so the two cannot
+// share a mapping table.
+package oneapi
+
+import (
+	"fmt"
+
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
+// ChannelType represents the channel type enum in One API.
+type ChannelType int
+
+// Channel type constants from One API relay/channeltype/define.go
+const (
+	ChannelTypeUnknown                ChannelType = 0
+	ChannelTypeOpenAI                 ChannelType = 1
+	ChannelTypeAPI2D                  ChannelType = 2
+	ChannelTypeAzure                  ChannelType = 3
+	ChannelTypeCloseAI                ChannelType = 4
+	ChannelTypeOpenAISB               ChannelType = 5
+	ChannelTypeOpenAIMax              ChannelType = 6
+	ChannelTypeOhMyGPT                ChannelType = 7
+	ChannelTypeCustom                 ChannelType = 8
+	ChannelTypeAILS                   ChannelType = 9
+	ChannelTypeAIProxy                ChannelType = 10
+	ChannelTypePaLM                   ChannelType = 11
+	ChannelTypeAPI2GPT                ChannelType = 12
+	ChannelTypeAIGC2D                 ChannelType = 13
+	ChannelTypeAnthropic              ChannelType = 14
+	ChannelTypeBaidu                  ChannelType = 15
+	ChannelTypeZhipu                  ChannelType = 16
+	ChannelTypeAli                    ChannelType = 17
+	ChannelTypeXunfei                 ChannelType = 18
+	ChannelType360                    ChannelType = 19
+	ChannelTypeOpenRouter             ChannelType = 20
+	ChannelTypeAIProxyLibrary         ChannelType = 21
+	ChannelTypeFastGPT                ChannelType = 22
+	ChannelTypeTencent                ChannelType = 23
+	ChannelTypeGemini                 ChannelType = 24
+	ChannelTypeMoonshot               ChannelType = 25
+	ChannelTypeBaichuan               ChannelType = 26
+	ChannelTypeMinimax                ChannelType = 27
+	ChannelTypeMistral                ChannelType = 28
+	ChannelTypeGroq                   ChannelType = 29
+	ChannelTypeOllama                 ChannelType = 30
+	ChannelTypeLingYiWanWu            ChannelType = 31
+	ChannelTypeStepFun                ChannelType = 32
+	ChannelTypeAwsClaude              ChannelType = 33
+	ChannelTypeCoze                   ChannelType = 34
+	ChannelTypeCohere                 ChannelType = 35
+	ChannelTypeDeepSeek               ChannelType = 36
+	ChannelTypeCloudflare             ChannelType = 37
+	ChannelTypeDeepL                  ChannelType = 38
+	ChannelTypeTogetherAI             ChannelType = 39
+	ChannelTypeDoubao                 ChannelType = 40
+	ChannelTypeNovita                 ChannelType = 41
+	ChannelTypeVertexAI               ChannelType = 42
+	ChannelTypeProxy                  ChannelType = 43
+	ChannelTypeSiliconFlow            ChannelType = 44
+	ChannelTypeXai                    ChannelType = 45
+	ChannelTypeReplicate              ChannelType = 46
+	ChannelTypeBaiduV2                ChannelType = 47
+	ChannelTypeXunfeiV2               ChannelType = 48
+	ChannelTypeAliBailian             ChannelType = 49
+	ChannelTypeOpenAICompatible       ChannelType = 50
+	ChannelTypeGeminiOpenAICompatible ChannelType = 51
+)
+
+// channelTypeMapping maps One API channel type int to EZ-API provider type string.
+var channelTypeMapping = map[ChannelType]string{
+	ChannelTypeUnknown:                "custom",
+	ChannelTypeOpenAI:                 "openai",
+	ChannelTypeAPI2D:                  "openai",
+	ChannelTypeAzure:                  "azure",
+	ChannelTypeCloseAI:                "openai",
+	ChannelTypeOpenAISB:               "openai",
+	ChannelTypeOpenAIMax:              "openai",
+	ChannelTypeOhMyGPT:                "openai",
+	ChannelTypeCustom:                 "custom",
+	ChannelTypeAILS:                   "openai",
+	ChannelTypeAIProxy:                "openai",
+	ChannelTypePaLM:                   "palm",
+	ChannelTypeAPI2GPT:                "openai",
+	ChannelTypeAIGC2D:                 "openai",
+	ChannelTypeAnthropic:              "anthropic",
+	ChannelTypeBaidu:                  "baidu",
+	ChannelTypeZhipu:                  "zhipu",
+	ChannelTypeAli:                    "ali",
+	ChannelTypeXunfei:                 "xunfei",
+	ChannelType360:                    "360",
+	ChannelTypeOpenRouter:             "openrouter",
+	ChannelTypeAIProxyLibrary:         "openai",
+	ChannelTypeFastGPT:                "openai",
+	ChannelTypeTencent:                "tencent",
+	ChannelTypeGemini:                 "gemini",
+	ChannelTypeMoonshot:               "moonshot",
+	ChannelTypeBaichuan:               "baichuan",
+	ChannelTypeMinimax:                "minimax",
+	ChannelTypeMistral:                "mistral",
+	ChannelTypeGroq:                   "groq",
+	ChannelTypeOllama:                 "ollama",
+	ChannelTypeLingYiWanWu:            "lingyiwanwu",
+	ChannelTypeStepFun:                "stepfun",
+	ChannelTypeAwsClaude:              "aws",
+	ChannelTypeCoze:                   "coze",
+	ChannelTypeCohere:                 "cohere",
+	ChannelTypeDeepSeek:               "deepseek",
+	ChannelTypeCloudflare:             "cloudflare",
+	ChannelTypeDeepL:                  "deepl",
+	ChannelTypeTogetherAI:             "openai",
+	ChannelTypeDoubao:                 "volcengine",
+	ChannelTypeNovita:                 "openai",
+	ChannelTypeVertexAI:               "vertex",
+	ChannelTypeProxy:                  "custom",
+	ChannelTypeSiliconFlow:            "siliconflow",
+	ChannelTypeXai:                    "xai",
+	ChannelTypeReplicate:              "replicate",
+	ChannelTypeBaiduV2:                "baidu",
+	ChannelTypeXunfeiV2:               "xunfei",
+	ChannelTypeAliBailian:             "ali",
+	ChannelTypeOpenAICompatible:       "openai",
+	ChannelTypeGeminiOpenAICompatible: "gemini",
+}
+
+// ToProviderType converts a One API channel type to EZ-API provider type string.
+// Returns ("custom", false) if the type is unknown.
+func (t ChannelType) ToProviderType() (string, bool) {
+	providerType, ok := channelTypeMapping[t]
+	if !ok {
+		return "custom", false
+	}
+	return providerType, true
+}
+
+// MapChannelType maps an integer channel type to EZ-API provider type.
+// Returns the mapped type and a boolean indicating if mapping was successful.
+func MapChannelType(typeID int) (string, bool) {
+	return ChannelType(typeID).ToProviderType()
+}
+
+// TypeMapping is the compiled-in channel type mapping with user-supplied
+// overrides (see source.TypeMap) applied on top. The zero value is the
+// compiled-in mapping.
+type TypeMapping struct {
+	overrides map[ChannelType]source.TypeOverride
+}
+
+// NewTypeMapping creates a mapping with overrides by channel type ID. Type
+// IDs that are not built in must set a provider type.
+func NewTypeMapping(overrides map[int]source.TypeOverride) (TypeMapping, error) {
+	result := make(map[ChannelType]source.TypeOverride, len(overrides))
+	for id, o := range overrides {
+		if _, ok := channelTypeMapping[ChannelType(id)]; !ok && o.Provider == "" {
+			return TypeMapping{}, fmt.Errorf("type %d is not a built-in One API channel type and needs a provider", id)
+		}
+		result[ChannelType(id)] = o
+	}
+	return TypeMapping{overrides: result}, nil
+}
+
+// ProviderType is like ChannelType.ToProviderType, honoring the overrides.
+func (m TypeMapping) ProviderType(t ChannelType) (string, bool) {
+	if o := m.overrides[t]; o.Provider != "" {
+		return o.Provider, true
+	}
+	return t.ToProviderType()
+}
+
+// DefaultBaseURL returns the overridden base URL for channels of type t that
+// have none, or "".
+func (m TypeMapping) DefaultBaseURL(t ChannelType) string {
+	return m.overrides[t].BaseURL
+}
@@ -0,0 +1,263 @@
+// Package oneapi provides database connectors for One API.
+package oneapi
+
+import (
+	"fmt"
+
+	"github.com/EZ-Api/exporter/internal/source/database"
+	"gorm.io/gorm"
+)
+
+// Connector provides database access for One API.
+type Connector struct {
+	db     *gorm.DB
+	config database.Config
+}
+
+// NewConnector creates a new database connector.
+func NewConnector(config database.Config) (*Connector, error) {
+	db, err := database.Open(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Connector{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+// Close closes the database connection.
+func (c *Connector) Close() error {
+	return database.Close(c.db)
+}
+
+// Ping tests the database connection.
+func (c *Connector) Ping() error {
+	return database.Ping(c.db)
+}
+
+// withDB returns a connector that queries through db (e.g. a transaction).
+func (c *Connector) withDB(db *gorm.DB) *Connector {
+	return &Connector{
+		db:     db,
+		config: c.config,
+	}
+}
+
+// GetDB returns the underlying GORM database instance.
+func (c *Connector) GetDB() *gorm.DB {
+	return c.db
+}
+
+// GetAllChannels retrieves all channels from the database.
+func (c *Connector) GetAllChannels() ([]Channel, error) {
+	var channels []Channel
+	err := c.db.Find(&channels).Error
+	return channels, err
+}
+
+// EachChannelBatch reads all channels in batches ordered by ID and calls fn for each batch.
+func (c *Connector) EachChannelBatch(batchSize int, fn func([]Channel) error) error {
+	var channels []Channel
+	return c.db.FindInBatches(&channels, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(channels)
+	}).Error
+}
+
+// EachChannelBatchSince reads channels created or tested at or after since
+// (Unix time) in batches ordered by ID and calls fn for each batch.
+func (c *Connector) EachChannelBatchSince(since int64, batchSize int, fn func([]Channel) error) error {
+	var channels []Channel
+	return c.db.Where("created_time >= ? OR test_time >= ?", since, since).
+		FindInBatches(&channels, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(channels)
+		}).Error
+}
+
+// GetChannelNameCounts returns how many channels use each channel name.
+func (c *Connector) GetChannelNameCounts() (map[string]int, error) {
+	var names []string
+	if err := c.db.Model(&Channel{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name]++
+	}
+	return counts, nil
+}
+
+// GetChannelIDs returns the IDs of all channels.
+func (c *Connector) GetChannelIDs() ([]int, error) {
+	var ids []int
+	err := c.db.Model(&Channel{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// EachUserWithTokensBatch reads users who have at least one token in batches
+// ordered by ID and calls fn for each batch.
+func (c *Connector) EachUserWithTokensBatch(batchSize int, fn func([]User) error) error {
+	var users []User
+	return c.db.Where("id IN (SELECT DISTINCT user_id FROM tokens)").
+		FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(users)
+		}).Error
+}
+
+// EachUserWithTokensBatchSince reads users who have a token created or
+// accessed at or after since (Unix time) in batches ordered by ID and calls
+// fn for each batch.
+func (c *Connector) EachUserWithTokensBatchSince(since int64, batchSize int, fn func([]User) error) error {
+	var users []User
+	return c.db.Where("id IN (SELECT DISTINCT user_id FROM tokens WHERE created_time >= ? OR accessed_time >= ?)", since, since).
+		FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(users)
+		}).Error
+}
+
+// GetUserIDs returns the IDs of all users not marked as deleted.
+// One API deletes users by setting their status.
+func (c *Connector) GetUserIDs() ([]int, error) {
+	var ids []int
+	err := c.db.Model(&User{}).Where("status <> ?", UserStatusDeleted).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetAdminUsers retrieves all admin and root users not marked as deleted,
+// ordered by ID.
+func (c *Connector) GetAdminUsers() ([]User, error) {
+	var users []User
+	err := c.db.Where("role >= ? AND status <> ?", RoleAdminUser, UserStatusDeleted).Order("id").Find(&users).Error
+	return users, err
+}
+
+// GetUsersWithTokens retrieves all users who have at least one token.
+func (c *Connector) GetUsersWithTokens() ([]User, error) {
+	var users []User
+	err := c.db.Where("id IN (SELECT DISTINCT user_id FROM tokens)").Find(&users).Error
+	return users, err
+}
+
+// EachTokenBatchByUser reads all tokens in batches ordered by (user_id, id)
+// and calls fn for each batch. It uses keyset pagination, so each batch is a
+// single indexed range query regardless of how deep into the table it is.
+func (c *Connector) EachTokenBatchByUser(batchSize int, fn func([]Token) error) error {
+	return c.eachTokenBatchByUser(batchSize, fn)
+}
+
+// EachTokenBatchByUserSince is like EachTokenBatchByUser but only reads
+// tokens created or accessed at or after since (Unix time).
+func (c *Connector) EachTokenBatchByUserSince(since int64, batchSize int, fn func([]Token) error) error {
+	return c.eachTokenBatchByUser(batchSize, fn, "(created_time >= ? OR accessed_time >= ?)", since, since)
+}
+
+// eachTokenBatchByUser pages through tokens matching the optional condition
+// (a query string followed by its arguments).
+func (c *Connector) eachTokenBatchByUser(batchSize int, fn func([]Token) error, cond ...interface{}) error {
+	lastUserID, lastID := 0, 0
+	first := true
+
+	for {
+		var tokens []Token
+		query := c.db.Order("user_id").Order("id").Limit(batchSize)
+		if len(cond) > 0 {
+			query = query.Where(cond[0], cond[1:]...)
+		}
+		if !first {
+			query = query.Where("(user_id > ? OR (user_id = ? AND id > ?))", lastUserID, lastUserID, lastID)
+		}
+		if err := query.Find(&tokens).Error; err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		if err := fn(tokens); err != nil {
+			return err
+		}
+
+		if len(tokens) < batchSize {
+			return nil
+		}
+		last := tokens[len(tokens)-1]
+		lastUserID, lastID = last.UserID, last.ID
+		first = false
+	}
+}
+
+// GetTokenIDs returns the IDs of all tokens.
+func (c *Connector) GetTokenIDs() ([]int, error) {
+	var ids []int
+	err := c.db.Model(&Token{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetTokensByUserID retrieves all tokens for a user.
+func (c *Connector) GetTokensByUserID(userID int) ([]Token, error) {
+	var tokens []Token
+	err := c.db.Where("user_id = ?", userID).Find(&tokens).Error
+	return tokens, err
+}
+
+// GetAllAbilities retrieves all abilities from the database.
+func (c *Connector) GetAllAbilities() ([]Ability, error) {
+	var abilities []Ability
+	err := c.db.Find(&abilities).Error
+	return abilities, err
+}
+
+// EachRedemptionBatch reads all redemption codes in batches ordered by ID
+// and calls fn for each batch.
+func (c *Connector) EachRedemptionBatch(batchSize int, fn func([]Redemption) error) error {
+	var redemptions []Redemption
+	return c.db.FindInBatches(&redemptions, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(redemptions)
+	}).Error
+}
+
+// EachRedemptionBatchSince reads redemption codes created or redeemed at or
+// after since (Unix time) in batches ordered by ID and calls fn for each batch.
+func (c *Connector) EachRedemptionBatchSince(since int64, batchSize int, fn func([]Redemption) error) error {
+	var redemptions []Redemption
+	return c.db.Where("created_time >= ? OR redeemed_time >= ?", since, since).
+		FindInBatches(&redemptions, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(redemptions)
+		}).Error
+}
+
+// DatabaseStats holds entity counts.
+type DatabaseStats struct {
+	Channels  int64
+	Tokens    int64
+	Users     int64
+	Abilities int64
+	DBType    database.Type
+}
+
+// GetStats returns counts of all entities.
+func (c *Connector) GetStats() (*DatabaseStats, error) {
+	stats := &DatabaseStats{
+		DBType: c.config.Type,
+	}
+
+	counts := []struct {
+		name  string
+		model interface{}
+		dest  *int64
+	}{
+		{"channels", &Channel{}, &stats.Channels},
+		{"tokens", &Token{}, &stats.Tokens},
+		{"users", &User{}, &stats.Users},
+		{"abilities", &Ability{}, &stats.Abilities},
+	}
+
+	for _, count := range counts {
+		if err := c.db.Model(count.model).Count(count.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", count.name, err)
+		}
+	}
+
+	return stats, nil
+}
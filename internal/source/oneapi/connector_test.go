@@ -0,0 +1,166 @@
+package oneapi
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/source/database"
+	"gorm.io/gorm/logger"
+)
+
+// newTestConnector opens a connector on a fresh SQLite database with the
+// One API tables.
+func newTestConnector(tb testing.TB) *Connector {
+	tb.Helper()
+
+	c, err := NewConnector(database.Config{
+		Type:     database.TypeSQLite,
+		DSN:      filepath.Join(tb.TempDir(), "one_api.db"),
+		LogLevel: logger.Silent,
+	})
+	if err != nil {
+		tb.Fatalf("open: %v", err)
+	}
+	tb.Cleanup(func() { c.Close() })
+
+	if err := c.GetDB().AutoMigrate(&Channel{}, &Token{}, &User{}, &Ability{}, &Redemption{}); err != nil {
+		tb.Fatalf("migrate: %v", err)
+	}
+	return c
+}
+
+// seedTokens inserts count tokens spread unevenly over users, in an order
+// that interleaves users so that token IDs are not grouped by user.
+func seedTokens(tb testing.TB, c *Connector, count int) {
+	tb.Helper()
+
+	const users = 37
+	tokens := make([]Token, count)
+	for i := range tokens {
+		// Quadratic spread: low user IDs get many more tokens than high ones
+		tokens[i] = Token{
+			UserID:      (i*i)%users + 1,
+			Key:         fmt.Sprintf("sk-%045d", i),
+			Name:        fmt.Sprintf("token-%d", i),
+			CreatedTime: int64(i),
+		}
+	}
+	if err := c.GetDB().CreateInBatches(tokens, 500).Error; err != nil {
+		tb.Fatalf("seed tokens: %v", err)
+	}
+}
+
+func TestEachTokenBatchByUser(t *testing.T) {
+	c := newTestConnector(t)
+	const count = 3000
+	seedTokens(t, c, count)
+
+	var want []int
+	if err := c.GetDB().Model(&Token{}).Order("user_id").Order("id").Pluck("id", &want).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, batchSize := range []int{1, 7, 100, 999, 1000, count, count + 1} {
+		t.Run(fmt.Sprintf("batch=%d", batchSize), func(t *testing.T) {
+			var got []int
+			spanning := 0
+			prevLastUser := -1
+			err := c.EachTokenBatchByUser(batchSize, func(batch []Token) error {
+				if len(batch) == 0 || len(batch) > batchSize {
+					t.Fatalf("batch of %d tokens, batch size %d", len(batch), batchSize)
+				}
+				if batch[0].UserID == prevLastUser {
+					spanning++
+				}
+				prevLastUser = batch[len(batch)-1].UserID
+				for _, tok := range batch {
+					got = append(got, tok.ID)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d tokens, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("token %d: got ID %d, want %d", i, got[i], want[i])
+				}
+			}
+			if batchSize < count && spanning == 0 {
+				t.Errorf("no user's tokens spanned a batch boundary; fixture does not cover that case")
+			}
+		})
+	}
+}
+
+func TestEachTokenBatchByUserSince(t *testing.T) {
+	c := newTestConnector(t)
+	seedTokens(t, c, 500)
+
+	const since = 400
+	n := 0
+	err := c.EachTokenBatchByUserSince(since, 9, func(batch []Token) error {
+		for _, tok := range batch {
+			if tok.CreatedTime < since {
+				t.Errorf("token %d created at %d, before %d", tok.ID, tok.CreatedTime, since)
+			}
+		}
+		n += len(batch)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 100 {
+		t.Errorf("got %d tokens, want 100", n)
+	}
+}
+
+// BenchmarkEachTokenBatchByUser compares the per-user token queries used
+// before keyset pagination with the keyset path.
+func BenchmarkEachTokenBatchByUser(b *testing.B) {
+	c := newTestConnector(b)
+	const count = 5000
+	seedTokens(b, c, count)
+
+	b.Run("per-user", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var userIDs []int
+			if err := c.GetDB().Model(&Token{}).Distinct("user_id").Order("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+				b.Fatal(err)
+			}
+			n := 0
+			for _, id := range userIDs {
+				tokens, err := c.GetTokensByUserID(id)
+				if err != nil {
+					b.Fatal(err)
+				}
+				n += len(tokens)
+			}
+			if n != count {
+				b.Fatalf("read %d tokens, want %d", n, count)
+			}
+		}
+	})
+
+	b.Run("keyset", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			n := 0
+			err := c.EachTokenBatchByUser(1000, func(batch []Token) error {
+				n += len(batch)
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if n != count {
+				b.Fatalf("read %d tokens, want %d", n, count)
+			}
+		}
+	})
+}
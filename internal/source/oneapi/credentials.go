@@ -0,0 +1,142 @@
+package oneapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
+// credentials is a channel key decoded into a provider API key and options.
+type credentials struct {
+	baseURL string
+	apiKey  string
+	options *schema.ProviderOptions
+	decoded []string // Config fields moved into the provider
+}
+
+// decodeCredentials decodes the credentials and options One API stores in
+// the channel key, config and other fields. Missing or unparsable values are
+// left unset with a warning; keys are never part of the warning.
+func (e *Exporter) decodeCredentials(ch Channel, baseURL string) credentials {
+	c := credentials{baseURL: baseURL, apiKey: ch.Key}
+	var options schema.ProviderOptions
+
+	// Invalid config JSON is reported as unmapped config
+	var config ChannelConfig
+	if strings.TrimSpace(ch.Config) != "" {
+		_ = json.Unmarshal([]byte(ch.Config), &config)
+	}
+	other := ""
+	if ch.Other != nil {
+		other = strings.TrimSpace(*ch.Other)
+	}
+
+	switch ChannelType(ch.Type) {
+	case ChannelTypeAzure:
+		c.baseURL, options.Deployment = source.AzureDeployment(baseURL)
+		options.APIVersion = config.APIVersion
+		if options.APIVersion == "" {
+			options.APIVersion = other
+		}
+		c.decoded = []string{"api_version"}
+
+	case ChannelTypeAwsClaude:
+		c.decoded = []string{"region", "ak", "sk"}
+		if config.AK != "" || config.SK != "" {
+			options.CredentialType = schema.CredentialAccessKey
+			options.AccessKeyID, c.apiKey, options.Region = config.AK, config.SK, config.Region
+			if config.AK == "" || config.SK == "" || config.Region == "" {
+				e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "config", scrubConfig(ch.Config), fmt.Sprintf(
+					"Channel '%s' (ID=%d) config lacks the AWS access key ID, secret access key or region",
+					ch.Name, ch.ID,
+				))
+			}
+			break
+		}
+		accessKeyID, secret, region, err := source.SplitAWSKey(ch.Key)
+		if err != nil {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "key", nil, fmt.Sprintf(
+				"Channel '%s' (ID=%d) has no AWS credentials in config and its key is not a valid AWS key, exported as is: %v",
+				ch.Name, ch.ID, err,
+			))
+			break
+		}
+		c.apiKey, options.Region, options.AccessKeyID = secret, region, accessKeyID
+		options.CredentialType = schema.CredentialAccessKey
+		if accessKeyID == "" {
+			options.CredentialType = schema.CredentialAPIKey
+		}
+
+	case ChannelTypeVertexAI:
+		c.decoded = []string{"region", "vertex_ai_project_id", "vertex_ai_adc"}
+		options.Region = config.Region
+		options.Project = config.VertexAIProjectID
+		if config.VertexAIADC != "" {
+			c.apiKey = config.VertexAIADC
+		}
+		if !source.IsServiceAccountKey(c.apiKey) {
+			if c.apiKey != "" {
+				options.CredentialType = schema.CredentialAPIKey
+			}
+			break
+		}
+		options.CredentialType = schema.CredentialServiceAccount
+		project, err := source.ServiceAccountProject(c.apiKey)
+		if err != nil && options.Project == "" {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "config", scrubConfig(ch.Config), fmt.Sprintf(
+				"Channel '%s' (ID=%d): %v, project not migrated",
+				ch.Name, ch.ID, err,
+			))
+		}
+		if options.Project == "" {
+			options.Project = project
+		}
+
+	case ChannelTypeCloudflare:
+		c.decoded = []string{"user_id"}
+		options.AccountID = config.UserID
+		if options.AccountID == "" {
+			e.warnChannel(ch, schema.WarnInvalidCredentials, schema.SeverityError, "config", scrubConfig(ch.Config), fmt.Sprintf(
+				"Channel '%s' (ID=%d) has no Cloudflare account ID (config user_id)",
+				ch.Name, ch.ID,
+			))
+		}
+	}
+
+	c.options = source.NonEmptyOptions(options)
+	return c
+}
+
+// unmappedConfig returns the channel config without the fields in decoded,
+// credentials and empty values, and whether anything is left. Configs that
+// are not JSON objects are left entirely.
+func unmappedConfig(config string, decoded []string) (string, bool) {
+	if strings.TrimSpace(config) == "" {
+		return "", false
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(config), &fields); err != nil {
+		return "", true
+	}
+	for _, name := range append(decoded, schema.BackupConfigSecretFields...) {
+		delete(fields, name)
+	}
+	for name, value := range fields {
+		if string(value) == `""` || string(value) == "null" {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", true
+	}
+	return string(data), true
+}
@@ -0,0 +1,517 @@
+// Package oneapi provides the export logic for One API data.
+package oneapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source"
+)
+
+// SourceType is the value recorded in schema.Source.Type for One API exports.
+const SourceType = "oneapi"
+
+// ExporterConfig holds configuration for the exporter.
+type ExporterConfig struct {
+	IncludeTokens         bool    // Whether to include tokens in export
+	IncludeAbilities      bool    // Whether to include abilities (bindings)
+	IncludeRedemptions    bool    // Whether to include redemption codes
+	IncludeAdministrators bool    // Whether to include admin and root users
+	BatchSize             int     // Rows read per query (0 = DefaultBatchSize)
+	Verbose               bool    // Enable verbose logging
+	Backup                string  // Policy for _original backups ("" = source.DefaultBackup)
+	QuotaPerUnit          float64 // Quota units per balance unit (0 = source.DefaultQuotaPerUnit)
+	MaxChildKeys          int     // Child keys allowed per master (0 = unset)
+	GlobalQPS             int     // QPS limit per master (0 = unset)
+
+	TypeMapping TypeMapping // Channel type mapping (zero value = built-in)
+
+	Since    time.Time        // Only export rows changed at or after Since (zero = full export)
+	Baseline *source.Baseline // Previous export, for detecting deletes (optional)
+}
+
+// DefaultBatchSize is the number of rows read per query when streaming tables.
+const DefaultBatchSize = 1000
+
+// Exporter handles the export process from One API.
+type Exporter struct {
+	connector *Connector
+	config    ExporterConfig
+	sink      schema.Sink
+
+	channelNames source.ChannelNames // Number of channels using each name
+}
+
+// NewExporter creates a new exporter instance.
+func NewExporter(connector *Connector, config ExporterConfig) *Exporter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.Backup == "" {
+		config.Backup = source.DefaultBackup
+	}
+	if config.QuotaPerUnit <= 0 {
+		config.QuotaPerUnit = source.DefaultQuotaPerUnit
+	}
+	return &Exporter{
+		connector: connector,
+		config:    config,
+	}
+}
+
+// Export performs the full export process and collects the result in memory.
+func (e *Exporter) Export() (*schema.ExportResult, error) {
+	result := schema.NewExportResult()
+	result.Source.Type = SourceType
+	if err := e.ExportTo(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExportTo performs the full export process, sending entities to sink as
+// they are read.
+func (e *Exporter) ExportTo(sink schema.Sink) error {
+	e.sink = sink
+
+	// Export channels -> providers
+	if err := e.exportChannels(); err != nil {
+		return fmt.Errorf("failed to export channels: %w", err)
+	}
+
+	// Export users and tokens -> masters and keys
+	if e.config.IncludeTokens {
+		if err := e.exportUsersAndTokens(); err != nil {
+			return fmt.Errorf("failed to export users/tokens: %w", err)
+		}
+	}
+
+	// Export abilities -> bindings (optional)
+	if e.config.IncludeAbilities {
+		if err := e.exportAbilities(); err != nil {
+			return fmt.Errorf("failed to export abilities: %w", err)
+		}
+	}
+
+	// Export redemption codes (optional)
+	if e.config.IncludeRedemptions {
+		if err := e.exportRedemptions(); err != nil {
+			return fmt.Errorf("failed to export redemptions: %w", err)
+		}
+	}
+
+	// Export admin and root users -> administrators (optional)
+	if e.config.IncludeAdministrators {
+		if err := e.exportAdministrators(); err != nil {
+			return fmt.Errorf("failed to export administrators: %w", err)
+		}
+	}
+
+	// Export deleted rows -> tombstones (incremental exports only)
+	if e.incremental() {
+		if err := e.exportTombstones(); err != nil {
+			return fmt.Errorf("failed to export tombstones: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// incremental reports whether only changes since config.Since are exported.
+func (e *Exporter) incremental() bool {
+	return !e.config.Since.IsZero()
+}
+
+// exportChannels exports all channels as providers. Incremental exports
+// only include channels created or tested since config.Since.
+func (e *Exporter) exportChannels() error {
+	// Names are counted over all channels, so incremental exports rename
+	// the same channels as full ones
+	names, err := e.connector.GetChannelNameCounts()
+	if err != nil {
+		return fmt.Errorf("failed to count channel names: %w", err)
+	}
+	e.channelNames = names
+
+	fn := func(channels []Channel) error {
+		for _, ch := range channels {
+			e.sink.AddProvider(e.channelToProvider(ch))
+		}
+		return nil
+	}
+
+	if e.incremental() {
+		return e.connector.EachChannelBatchSince(e.config.Since.Unix(), e.config.BatchSize, fn)
+	}
+	return e.connector.EachChannelBatch(e.config.BatchSize, fn)
+}
+
+// channelToProvider converts a One API channel to an EZ-API provider.
+// One API has no multi-key channels, so each channel maps to one provider.
+func (e *Exporter) channelToProvider(ch Channel) schema.Provider {
+	groups := source.ParseGroups(ch.Group)
+
+	providerType, typeOK := e.config.TypeMapping.ProviderType(ChannelType(ch.Type))
+	if !typeOK {
+		e.warnChannel(ch, schema.WarnUnknownChannelType, schema.SeverityWarning, "type", ch.Type, fmt.Sprintf(
+			"Channel '%s' (ID=%d) has unknown type %d, mapped to 'custom'",
+			ch.Name, ch.ID, ch.Type,
+		))
+	}
+
+	baseURL := ""
+	if ch.BaseURL != nil {
+		baseURL = *ch.BaseURL
+	}
+	if baseURL == "" {
+		baseURL = e.config.TypeMapping.DefaultBaseURL(ChannelType(ch.Type))
+	}
+
+	weight, priority := source.ChannelWeight(ch.Weight, ch.Priority)
+
+	// Credentials packed into key/config/other (Azure, AWS, Vertex AI, Cloudflare)
+	creds := e.decodeCredentials(ch, baseURL)
+
+	e.checkUnmappableFields(ch, creds.decoded)
+
+	// One API has no multi-key channels
+	name, originalName := e.channelNames.ProviderName(e.sink, sourceChannel(ch), 1), ""
+	if name != ch.Name {
+		originalName = ch.Name
+	}
+
+	// One API applies the model mapping once, without following chains
+	aliases := source.ModelAliases(e.sink, sourceChannel(ch), ch.ModelMapping, false)
+
+	return schema.Provider{
+		OriginalID:   ch.ID,
+		Name:         name,
+		OriginalName: originalName,
+		Type:         providerType,
+		BaseURL:      creds.baseURL,
+		APIKey:       creds.apiKey,
+		Models:       source.ParseModels(ch.Models),
+		PrimaryGroup: groups[0],
+		AllGroups:    groups,
+		Weight:       weight,
+		Priority:     priority,
+		Status:       MapChannelStatus(ch.Status),
+		AutoBan:      true, // One API always auto-disables failing channels
+		ModelAliases: aliases,
+		Options:      creds.options,
+		Original:     e.createOriginalBackup(ch),
+	}
+}
+
+// sourceChannel identifies a channel in warnings.
+func sourceChannel(ch Channel) source.Channel {
+	return source.Channel{ID: ch.ID, Name: ch.Name}
+}
+
+// warnChannel records a warning about a field of a channel, with the
+// field's original value.
+func (e *Exporter) warnChannel(ch Channel, code, severity, field string, value interface{}, msg string) {
+	sourceChannel(ch).Warn(e.sink, code, severity, field, value, msg)
+}
+
+// checkUnmappableFields checks for fields that cannot be mapped and adds
+// warnings. decoded lists the config fields already moved into the provider.
+func (e *Exporter) checkUnmappableFields(ch Channel, decoded []string) {
+	source.CheckRouting(e.sink, sourceChannel(ch), ch.Group, ch.Priority, e.config.IncludeAbilities)
+
+	if config, ok := unmappedConfig(ch.Config, decoded); ok {
+		e.warnChannel(ch, schema.WarnUnmappedConfig, schema.SeverityWarning, "config", config, fmt.Sprintf(
+			"Channel '%s' (ID=%d) has config which is not migrated",
+			ch.Name, ch.ID,
+		))
+	}
+
+	if ch.SystemPrompt != nil && *ch.SystemPrompt != "" {
+		e.warnChannel(ch, schema.WarnUnmappedSystemPrompt, schema.SeverityWarning, "system_prompt", *ch.SystemPrompt, fmt.Sprintf(
+			"Channel '%s' (ID=%d) has system_prompt which is not supported in EZ-API",
+			ch.Name, ch.ID,
+		))
+	}
+}
+
+// channelUnmappableFields are the channel fields EZ-API has no equivalent
+// for, kept by the unmappable backup policy.
+var channelUnmappableFields = []string{"model_mapping", "config", "system_prompt", "other"}
+
+// createOriginalBackup creates a JSON backup of original channel data
+// according to the backup policy. Except for the full policy, credentials
+// are also removed from the channel config.
+func (e *Exporter) createOriginalBackup(ch Channel) json.RawMessage {
+	if e.config.Backup != schema.BackupFull {
+		ch.Config = scrubConfig(ch.Config)
+	}
+	return source.Backup(e.config.Backup, ch, schema.BackupSecretFields, channelUnmappableFields)
+}
+
+// scrubConfig removes credentials from a channel config JSON. Configs that
+// are not JSON objects are dropped, since their credentials cannot be located.
+func scrubConfig(config string) string {
+	if strings.TrimSpace(config) == "" {
+		return config
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(config), &fields); err != nil {
+		return ""
+	}
+	for _, name := range schema.BackupConfigSecretFields {
+		delete(fields, name)
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// exportUsersAndTokens exports users and tokens as masters and keys.
+// Users are read first to emit all masters, then tokens are scanned once in
+// (user_id, id) order and matched to their master in memory.
+//
+// Incremental exports only include tokens created or accessed since
+// config.Since, together with the masters they belong to.
+func (e *Exporter) exportUsersAndTokens() error {
+	eachUserBatch := e.connector.EachUserWithTokensBatch
+	eachTokenBatch := e.connector.EachTokenBatchByUser
+	if e.incremental() {
+		since := e.config.Since.Unix()
+		eachUserBatch = func(batchSize int, fn func([]User) error) error {
+			return e.connector.EachUserWithTokensBatchSince(since, batchSize, fn)
+		}
+		eachTokenBatch = func(batchSize int, fn func([]Token) error) error {
+			return e.connector.EachTokenBatchByUserSince(since, batchSize, fn)
+		}
+	}
+
+	masters := source.NewMasters(e.sink)
+	err := eachUserBatch(e.config.BatchSize, func(users []User) error {
+		for _, user := range users {
+			masters.Add(e.userToMaster(user))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get users: %w", err)
+	}
+
+	err = eachTokenBatch(e.config.BatchSize, func(tokens []Token) error {
+		for _, token := range tokens {
+			if master, ok := masters.TokenMaster(token.UserID); ok {
+				e.sink.AddKey(e.tokenToKey(token, master))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get tokens: %w", err)
+	}
+
+	masters.Warn()
+	return nil
+}
+
+// exportAdministrators exports admin and root users as administrators.
+// There are few of them and their rows carry no change time, so they are
+// always exported in full.
+func (e *Exporter) exportAdministrators() error {
+	users, err := e.connector.GetAdminUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		e.sink.AddAdministrator(userToAdministrator(user))
+	}
+	return nil
+}
+
+// userToAdministrator converts a One API admin user to an EZ-API
+// administrator. The access token itself is not exported.
+func userToAdministrator(user User) schema.Administrator {
+	return schema.Administrator{
+		Name:           user.Username,
+		DisplayName:    user.DisplayName,
+		Email:          user.Email,
+		Role:           MapUserRole(user.Role),
+		Status:         MapUserStatus(user.Status),
+		HasAccessToken: user.AccessToken != "",
+		SourceUserID:   user.ID,
+		Identities: source.Identities(
+			schema.Identity{Provider: "github", Subject: user.GitHubID},
+			schema.Identity{Provider: "lark", Subject: user.LarkID},
+			schema.Identity{Provider: "oidc", Subject: user.OidcID},
+			schema.Identity{Provider: "wechat", Subject: user.WeChatID},
+		),
+	}
+}
+
+// userToMaster converts a One API user to an EZ-API master.
+func (e *Exporter) userToMaster(user User) schema.Master {
+	group := user.Group
+	if group == "" {
+		group = "default"
+	}
+
+	return schema.Master{
+		Name:             user.Username,
+		Group:            group,
+		Namespaces:       []string{group},
+		DefaultNamespace: group,
+		MaxChildKeys:     e.config.MaxChildKeys,
+		GlobalQPS:        e.config.GlobalQPS,
+		Status:           MapUserStatus(user.Status),
+		Role:             MapUserRole(user.Role),
+		Quota:            user.Quota,
+		QuotaUsed:        user.UsedQuota,
+		Balance:          source.Balance(user.Quota, e.config.QuotaPerUnit),
+		BalanceUsed:      source.Balance(user.UsedQuota, e.config.QuotaPerUnit),
+		RequestCount:     int64(user.RequestCount),
+		AffCode:          user.AffCode,
+		SourceUserID:     user.ID,
+		SourceEmail:      user.Email,
+		InviterUserID:    user.InviterID,
+	}
+}
+
+// tokenToKey converts a One API token to an EZ-API key.
+// One API tokens have no group of their own; they inherit the user's group.
+func (e *Exporter) tokenToKey(token Token, master schema.Master) schema.Key {
+	key := schema.Key{
+		MasterRef:               master.Name,
+		OriginalToken:           token.Key,
+		Group:                   master.Group,
+		Status:                  MapTokenStatus(token.Status),
+		Scopes:                  []string{"chat:*", "completions:*"},
+		Namespaces:              []string{master.Group},
+		UnlimitedQuota:          token.UnlimitedQuota,
+		OriginalID:              token.ID,
+		TokenPlaintextAvailable: true,
+	}
+
+	// A non-empty models list restricts the token to those models
+	if token.Models != nil {
+		if models := source.ParseModels(*token.Models); len(models) > 0 {
+			key.ModelLimitsEnabled = true
+			key.ModelLimits = models
+		}
+	}
+
+	if token.ExpiredTime > 0 && token.ExpiredTime != -1 {
+		key.ExpiresAt = TimestampToTime(token.ExpiredTime)
+	}
+
+	// Subnets map to the IP whitelist
+	if token.Subnet != nil && *token.Subnet != "" {
+		for _, subnet := range strings.Split(*token.Subnet, ",") {
+			if subnet = strings.TrimSpace(subnet); subnet != "" {
+				key.AllowIPs = append(key.AllowIPs, subnet)
+			}
+		}
+	}
+
+	if !token.UnlimitedQuota {
+		quota := token.RemainQuota
+		key.QuotaLimit = &quota
+		used := token.UsedQuota
+		key.QuotaUsed = &used
+	}
+
+	return key
+}
+
+// exportAbilities exports abilities as bindings and failover groups.
+func (e *Exporter) exportAbilities() error {
+	abilities, err := e.connector.GetAllAbilities()
+	if err != nil {
+		return err
+	}
+
+	routes := make([]source.Ability, 0, len(abilities))
+	for _, ab := range abilities {
+		routes = append(routes, abilityToSource(ab))
+	}
+	source.ExportAbilities(e.sink, routes)
+	return nil
+}
+
+// abilityToSource converts a One API ability to a route. One API picks
+// uniformly among the channels of a tier, so members have no weight.
+func abilityToSource(ab Ability) source.Ability {
+	var priority int64
+	if ab.Priority != nil {
+		priority = *ab.Priority
+	}
+	return source.Ability{
+		Route: source.Route{
+			Group:     ab.Group,
+			Model:     ab.Model,
+			ChannelID: ab.ChannelID,
+			Priority:  priority,
+		},
+		Enabled: ab.Enabled,
+	}
+}
+
+// exportRedemptions exports redemption codes. Incremental exports only
+// include codes created or redeemed since config.Since.
+func (e *Exporter) exportRedemptions() error {
+	codes := source.NewRedemptions(e.sink)
+	fn := func(redemptions []Redemption) error {
+		for _, r := range redemptions {
+			codes.Add(redemptionToSchema(r))
+		}
+		return nil
+	}
+
+	var err error
+	if e.incremental() {
+		err = e.connector.EachRedemptionBatchSince(e.config.Since.Unix(), e.config.BatchSize, fn)
+	} else {
+		err = e.connector.EachRedemptionBatch(e.config.BatchSize, fn)
+	}
+	if err != nil {
+		return err
+	}
+
+	codes.Warn()
+	return nil
+}
+
+// redemptionToSchema converts a One API redemption code. One API codes do
+// not expire and do not record who redeemed them.
+func redemptionToSchema(r Redemption) schema.Redemption {
+	return schema.Redemption{
+		OriginalID:    r.ID,
+		Name:          r.Name,
+		Code:          r.Key,
+		Quota:         r.Quota,
+		Status:        MapRedemptionStatus(r.Status),
+		CreatedAt:     TimestampToTime(r.CreatedTime),
+		CreatorUserID: r.UserID,
+		RedeemedAt:    TimestampToTime(r.RedeemedTime),
+	}
+}
+
+// exportTombstones exports channels, users and tokens deleted since the
+// baseline export. One API deletes rows outright (users are only marked as
+// deleted), so without a baseline there is nothing to compare against.
+func (e *Exporter) exportTombstones() error {
+	tables := []source.DeletedRows{
+		{Kind: schema.TombstoneProvider, Table: "channel", LiveIDs: e.connector.GetChannelIDs},
+	}
+	if e.config.IncludeTokens {
+		tables = append(tables,
+			source.DeletedRows{Kind: schema.TombstoneMaster, Table: "user", LiveIDs: e.connector.GetUserIDs},
+			source.DeletedRows{Kind: schema.TombstoneKey, Table: "token", LiveIDs: e.connector.GetTokenIDs},
+		)
+	}
+	return source.ExportTombstones(e.sink, e.config.Baseline, tables)
+}
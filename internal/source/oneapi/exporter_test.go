@@ -0,0 +1,47 @@
+package oneapi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+func TestExportDuplicateChannelNames(t *testing.T) {
+	c := newTestConnector(t)
+	channels := []Channel{
+		{ID: 1, Name: "openai", Key: "sk-1", Status: 1, Models: "gpt-4o", Group: "default"},
+		{ID: 2, Name: "claude", Key: "sk-2", Status: 1, Models: "claude-3", Group: "default"},
+		{ID: 3, Name: "openai", Key: "sk-3", Status: 1, Models: "gpt-4o", Group: "default"},
+	}
+	if err := c.GetDB().Create(&channels).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewExporter(c, ExporterConfig{}).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, p := range result.Data.Providers {
+		names = append(names, p.Name+"/"+p.OriginalName)
+	}
+	want := []string{"openai-1/openai", "claude/", "openai-3/openai"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("providers (name/original name) %v, want %v", names, want)
+	}
+
+	var renamed []int
+	for _, w := range result.WarningDetails {
+		if w.Code == schema.WarnDuplicateChannelName {
+			renamed = append(renamed, w.OriginalID)
+		}
+	}
+	if fmt.Sprint(renamed) != "[1 3]" {
+		t.Errorf("%s warnings for channels %v, want [1 3]", schema.WarnDuplicateChannelName, renamed)
+	}
+	if violations := result.Validate(); len(violations) > 0 {
+		t.Errorf("export is invalid: %v", violations)
+	}
+}
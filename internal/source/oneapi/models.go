@@ -0,0 +1,147 @@
+// Package oneapi defines data models for One API database tables.
+// These structures are based on the songquanpeng/one-api source code.
+// Compared to New API there is no multi-key support (no channel_info), no
+// param/header overrides, no token groups and no soft delete.
+package oneapi
+
+import (
+	"time"
+)
+
+// Channel represents the channels table in One API.
+// Source: model/channel.go
+type Channel struct {
+	ID                 int     `json:"id" gorm:"primaryKey"`
+	Type               int     `json:"type" gorm:"default:0"`                              // Channel type enum, see channel_type.go
+	Key                string  `json:"key" gorm:"type:text"`                               // API key (single key)
+	Status             int     `json:"status" gorm:"default:1"`                            // 1=enabled, 2=manually disabled, 3=auto disabled
+	Name               string  `json:"name" gorm:"index"`                                  // Channel name
+	Weight             *uint   `json:"weight" gorm:"default:0"`                            // Weight for load balancing
+	CreatedTime        int64   `json:"created_time" gorm:"bigint"`                         // Creation timestamp
+	TestTime           int64   `json:"test_time" gorm:"bigint"`                            // Last test timestamp
+	ResponseTime       int     `json:"response_time"`                                      // Response time in ms
+	BaseURL            *string `json:"base_url" gorm:"column:base_url;default:''"`         // Custom base URL
+	Other              *string `json:"other"`                                              // Other config (deprecated, e.g. Azure API version)
+	Balance            float64 `json:"balance"`                                            // Balance in USD
+	BalanceUpdatedTime int64   `json:"balance_updated_time" gorm:"bigint"`                 // Balance update timestamp
+	Models             string  `json:"models"`                                             // Supported models (comma separated)
+	Group              string  `json:"group" gorm:"type:varchar(32);default:'default'"`    // Groups (comma separated for multi-group)
+	UsedQuota          int64   `json:"used_quota" gorm:"bigint;default:0"`                 // Used quota
+	ModelMapping       *string `json:"model_mapping" gorm:"type:varchar(1024);default:''"` // Model mapping JSON (single level)
+	Priority           *int64  `json:"priority" gorm:"bigint;default:0"`                   // Priority
+	Config             string  `json:"config"`                                             // Channel config JSON (region, ak/sk, api_version, ...)
+	SystemPrompt       *string `json:"system_prompt" gorm:"type:text"`                     // Forced system prompt
+}
+
+// TableName returns the table name for Channel.
+func (Channel) TableName() string {
+	return "channels"
+}
+
+// ChannelConfig is the channel config JSON in One API.
+// Source: model/channel.go
+type ChannelConfig struct {
+	Region            string `json:"region,omitempty"`               // AWS or Vertex AI region
+	SK                string `json:"sk,omitempty"`                   // AWS secret access key
+	AK                string `json:"ak,omitempty"`                   // AWS access key ID
+	UserID            string `json:"user_id,omitempty"`              // Cloudflare account ID
+	APIVersion        string `json:"api_version,omitempty"`          // Azure API version
+	LibraryID         string `json:"library_id,omitempty"`           // Coze / FastGPT library ID
+	Plugin            string `json:"plugin,omitempty"`               // Plugin parameters
+	VertexAIProjectID string `json:"vertex_ai_project_id,omitempty"` // Vertex AI project ID
+	VertexAIADC       string `json:"vertex_ai_adc,omitempty"`        // Vertex AI service account JSON
+}
+
+// Token represents the tokens table in One API.
+// Source: model/token.go
+type Token struct {
+	ID             int     `json:"id" gorm:"primaryKey"`
+	UserID         int     `json:"user_id"`                               // Associated user
+	Key            string  `json:"key" gorm:"type:char(48);uniqueIndex"`  // Plaintext, 48 char random string
+	Status         int     `json:"status" gorm:"default:1"`               // 1=enabled, 2=disabled, 3=expired, 4=exhausted
+	Name           string  `json:"name" gorm:"index"`                     // Token name
+	CreatedTime    int64   `json:"created_time" gorm:"bigint"`            // Creation timestamp
+	AccessedTime   int64   `json:"accessed_time" gorm:"bigint"`           // Last access timestamp
+	ExpiredTime    int64   `json:"expired_time" gorm:"bigint;default:-1"` // Expiration timestamp, -1 = never
+	RemainQuota    int64   `json:"remain_quota" gorm:"bigint;default:0"`  // Remaining quota
+	UnlimitedQuota bool    `json:"unlimited_quota" gorm:"default:false"`  // Unlimited quota flag
+	UsedQuota      int64   `json:"used_quota" gorm:"bigint;default:0"`    // Used quota
+	Models         *string `json:"models" gorm:"type:text"`               // Allowed models (comma separated, empty = all)
+	Subnet         *string `json:"subnet" gorm:"default:''"`              // Allowed subnets (comma separated)
+}
+
+// TableName returns the table name for Token.
+func (Token) TableName() string {
+	return "tokens"
+}
+
+// User represents the users table in One API.
+// Source: model/user.go
+type User struct {
+	ID           int    `json:"id" gorm:"primaryKey"`
+	Username     string `json:"username" gorm:"unique;index"`                                      // Username
+	Password     string `json:"password" gorm:"not null"`                                          // Password (bcrypt hash)
+	DisplayName  string `json:"display_name" gorm:"index"`                                         // Display name
+	Role         int    `json:"role" gorm:"type:int;default:1"`                                    // 0=guest, 1=common, 10=admin, 100=root
+	Status       int    `json:"status" gorm:"type:int;default:1"`                                  // 1=enabled, 2=disabled, 3=deleted
+	Email        string `json:"email" gorm:"index"`                                                // Email
+	GitHubID     string `json:"github_id" gorm:"column:github_id;index"`                           // GitHub OAuth ID
+	LarkID       string `json:"lark_id" gorm:"column:lark_id;index"`                               // Lark OAuth ID
+	OidcID       string `json:"oidc_id" gorm:"column:oidc_id;index"`                               // OIDC ID
+	WeChatID     string `json:"wechat_id" gorm:"column:wechat_id;index"`                           // WeChat ID
+	AccessToken  string `json:"access_token" gorm:"type:char(32);column:access_token;uniqueIndex"` // System admin token
+	Quota        int64  `json:"quota" gorm:"bigint;default:0"`                                     // Remaining quota
+	UsedQuota    int64  `json:"used_quota" gorm:"bigint;default:0;column:used_quota"`              // Used quota
+	RequestCount int    `json:"request_count" gorm:"type:int;default:0"`                           // Request count
+	Group        string `json:"group" gorm:"type:varchar(32);default:'default'"`                   // User group
+	AffCode      string `json:"aff_code" gorm:"type:varchar(32);column:aff_code;uniqueIndex"`      // Affiliate code
+	InviterID    int    `json:"inviter_id" gorm:"type:int;column:inviter_id;index"`                // Inviter ID
+}
+
+// TableName returns the table name for User.
+func (User) TableName() string {
+	return "users"
+}
+
+// Ability represents the abilities table in One API.
+// Source: model/ability.go
+type Ability struct {
+	Group     string `json:"group" gorm:"type:varchar(32);primaryKey;autoIncrement:false"` // Group name (composite PK)
+	Model     string `json:"model" gorm:"primaryKey;autoIncrement:false"`                  // Model name (composite PK)
+	ChannelID int    `json:"channel_id" gorm:"primaryKey;autoIncrement:false;index"`       // Channel ID (composite PK)
+	Enabled   bool   `json:"enabled"`                                                      // Enabled flag
+	Priority  *int64 `json:"priority" gorm:"bigint;default:0;index"`                       // Priority
+}
+
+// TableName returns the table name for Ability.
+func (Ability) TableName() string {
+	return "abilities"
+}
+
+// Redemption represents the redemptions table in One API.
+// Source: model/redemption.go
+type Redemption struct {
+	ID           int    `json:"id" gorm:"primaryKey"`
+	UserID       int    `json:"user_id"`                              // Creator user ID
+	Key          string `json:"key" gorm:"type:char(32);uniqueIndex"` // Redemption code (32 chars)
+	Status       int    `json:"status" gorm:"default:1"`              // 1=enabled, 2=disabled, 3=used
+	Name         string `json:"name" gorm:"index"`                    // Redemption code name
+	Quota        int64  `json:"quota" gorm:"bigint;default:100"`      // Quota value
+	CreatedTime  int64  `json:"created_time" gorm:"bigint"`           // Creation timestamp
+	RedeemedTime int64  `json:"redeemed_time" gorm:"bigint"`          // Redemption timestamp
+}
+
+// TableName returns the table name for Redemption.
+func (Redemption) TableName() string {
+	return "redemptions"
+}
+
+// TimestampToTime converts a Unix timestamp to time.Time pointer.
+// Returns nil if timestamp is -1 (meaning never expires).
+func TimestampToTime(ts int64) *time.Time {
+	if ts == -1 || ts == 0 {
+		return nil
+	}
+	t := time.Unix(ts, 0)
+	return &t
+}
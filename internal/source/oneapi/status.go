@@ -0,0 +1,140 @@
+// Package oneapi provides status enum mapping.
+package oneapi
+
+// UserStatus represents user status enum in One API.
+type UserStatus int
+
+const (
+	UserStatusEnabled  UserStatus = 1
+	UserStatusDisabled UserStatus = 2
+	UserStatusDeleted  UserStatus = 3
+)
+
+// ToEzAPIStatus converts One API user status to EZ-API status string.
+func (s UserStatus) ToEzAPIStatus() string {
+	switch s {
+	case UserStatusEnabled:
+		return "active"
+	default:
+		return "suspended"
+	}
+}
+
+// TokenStatus represents token status enum in One API.
+type TokenStatus int
+
+const (
+	TokenStatusEnabled   TokenStatus = 1
+	TokenStatusDisabled  TokenStatus = 2
+	TokenStatusExpired   TokenStatus = 3
+	TokenStatusExhausted TokenStatus = 4
+)
+
+// ToEzAPIStatus converts One API token status to EZ-API status string.
+func (s TokenStatus) ToEzAPIStatus() string {
+	switch s {
+	case TokenStatusEnabled:
+		return "active"
+	case TokenStatusDisabled:
+		return "disabled"
+	case TokenStatusExpired:
+		return "expired"
+	case TokenStatusExhausted:
+		return "exhausted"
+	default:
+		return "disabled"
+	}
+}
+
+// ChannelStatus represents channel status enum in One API.
+type ChannelStatus int
+
+const (
+	ChannelStatusUnknown          ChannelStatus = 0
+	ChannelStatusEnabled          ChannelStatus = 1
+	ChannelStatusManuallyDisabled ChannelStatus = 2
+	ChannelStatusAutoDisabled     ChannelStatus = 3
+)
+
+// ToEzAPIStatus converts One API channel status to EZ-API status string.
+func (s ChannelStatus) ToEzAPIStatus() string {
+	if s == ChannelStatusEnabled {
+		return "active"
+	}
+	return "disabled"
+}
+
+// RedemptionStatus represents redemption code status enum in One API.
+type RedemptionStatus int
+
+const (
+	RedemptionStatusEnabled  RedemptionStatus = 1
+	RedemptionStatusDisabled RedemptionStatus = 2
+	RedemptionStatusUsed     RedemptionStatus = 3
+)
+
+// ToEzAPIStatus converts One API redemption status to EZ-API status string.
+func (s RedemptionStatus) ToEzAPIStatus() string {
+	switch s {
+	case RedemptionStatusEnabled:
+		return "active"
+	case RedemptionStatusUsed:
+		return "used"
+	default:
+		return "disabled"
+	}
+}
+
+// UserRole represents user role enum in One API.
+type UserRole int
+
+const (
+	RoleGuestUser  UserRole = 0
+	RoleCommonUser UserRole = 1
+	RoleAdminUser  UserRole = 10
+	RoleRootUser   UserRole = 100
+)
+
+// IsAdmin returns true if user is admin or root.
+func (r UserRole) IsAdmin() bool {
+	return r >= RoleAdminUser
+}
+
+// ToEzAPIRole converts One API user role to EZ-API role string.
+func (r UserRole) ToEzAPIRole() string {
+	switch {
+	case r >= RoleRootUser:
+		return "root"
+	case r >= RoleAdminUser:
+		return "admin"
+	case r >= RoleCommonUser:
+		return "user"
+	default:
+		return "guest"
+	}
+}
+
+// MapUserStatus maps integer user status to EZ-API status string.
+func MapUserStatus(status int) string {
+	return UserStatus(status).ToEzAPIStatus()
+}
+
+// MapUserRole maps integer user role to EZ-API role string.
+func MapUserRole(role int) string {
+	return UserRole(role).ToEzAPIRole()
+}
+
+// MapTokenStatus maps integer token status to EZ-API status string.
+func MapTokenStatus(status int) string {
+	return TokenStatus(status).ToEzAPIStatus()
+}
+
+// MapRedemptionStatus maps integer redemption status to EZ-API status string.
+func MapRedemptionStatus(status int) string {
+	return RedemptionStatus(status).ToEzAPIStatus()
+}
+
+// MapChannelStatus maps integer channel status to EZ-API status string.
+func MapChannelStatus(status int) string {
+	return ChannelStatus(status).ToEzAPIStatus()
+}
@@ -0,0 +1,30 @@
+package source
+
+import (
+	"fmt"
+	"math"
+)
+
+// Defaults for the master settings in ExportOptions.
+const (
+	// DefaultQuotaPerUnit is New API's and One API's default number of quota
+	// units per USD (QuotaPerUnit option).
+	DefaultQuotaPerUnit = 500000
+
+	DefaultMaxChildKeys = 10 // Child keys allowed per master
+	DefaultGlobalQPS    = 3  // QPS limit per master
+)
+
+// ValidQuotaPerUnit checks that a quota-per-unit ratio is positive and finite.
+func ValidQuotaPerUnit(perUnit float64) error {
+	if perUnit <= 0 || math.IsInf(perUnit, 0) || math.IsNaN(perUnit) {
+		return fmt.Errorf("quota per unit must be a positive number, got %v", perUnit)
+	}
+	return nil
+}
+
+// Balance converts a quota in source units to a balance, given the number of
+// quota units per balance unit.
+func Balance(quota int64, perUnit float64) float64 {
+	return float64(quota) / perUnit
+}
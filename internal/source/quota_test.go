@@ -0,0 +1,61 @@
+package source
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuotaDefaults(t *testing.T) {
+	// Must match the source systems' defaults and the documented flag defaults
+	if DefaultQuotaPerUnit != 500000 || DefaultMaxChildKeys != 10 || DefaultGlobalQPS != 3 {
+		t.Errorf("defaults %v, %d, %d, want 500000, 10, 3", DefaultQuotaPerUnit, DefaultMaxChildKeys, DefaultGlobalQPS)
+	}
+}
+
+func TestValidQuotaPerUnit(t *testing.T) {
+	for _, perUnit := range []float64{1, 0.5, DefaultQuotaPerUnit, 7.3e6} {
+		if err := ValidQuotaPerUnit(perUnit); err != nil {
+			t.Errorf("ValidQuotaPerUnit(%v): %v", perUnit, err)
+		}
+	}
+	for _, perUnit := range []float64{0, -500000, math.Inf(1), math.NaN()} {
+		if err := ValidQuotaPerUnit(perUnit); err == nil {
+			t.Errorf("ValidQuotaPerUnit(%v) accepted", perUnit)
+		}
+	}
+}
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		quota   int64
+		perUnit float64
+		want    float64
+	}{
+		{0, DefaultQuotaPerUnit, 0},
+		{1250000, DefaultQuotaPerUnit, 2.5},
+		{-500000, DefaultQuotaPerUnit, -1},
+		// Not rounded to cents: small quotas keep their value
+		{1, DefaultQuotaPerUnit, 0.000002},
+		{4999, DefaultQuotaPerUnit, 0.009998},
+		{333333, DefaultQuotaPerUnit, 0.666666},
+		// Custom ratios
+		{1000, 1, 1000},
+		{1000, 7.3e6, 1000 / 7.3e6},
+		{10, 3, 10.0 / 3},
+		{math.MaxInt32, 500, float64(math.MaxInt32) / 500},
+	}
+	for _, tt := range tests {
+		if got := Balance(tt.quota, tt.perUnit); got != tt.want {
+			t.Errorf("Balance(%d, %v) = %v, want %v", tt.quota, tt.perUnit, got, tt.want)
+		}
+	}
+
+	// The quota can be recovered from the balance
+	for _, perUnit := range []float64{DefaultQuotaPerUnit, 3, 7.3e6} {
+		for _, quota := range []int64{1, 7, 4999, 333333, 123456789, 1 << 40} {
+			if got := math.Round(Balance(quota, perUnit) * perUnit); got != float64(quota) {
+				t.Errorf("Balance(%d, %v) * %v rounds to %v", quota, perUnit, perUnit, got)
+			}
+		}
+	}
+}
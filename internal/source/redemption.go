@@ -0,0 +1,58 @@
+package source
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// Redemptions sends redemption codes to a sink. Used and expired codes are
+// exported with their status and reported in one warning each by Warn.
+type Redemptions struct {
+	sink          schema.Sink
+	now           time.Time
+	used, expired int
+}
+
+// NewRedemptions creates a redemption code exporter sending codes to sink.
+func NewRedemptions(sink schema.Sink) *Redemptions {
+	return &Redemptions{sink: sink, now: time.Now()}
+}
+
+// Add sends a redemption code to the sink. Active codes past their expiry
+// time are marked as expired.
+func (r *Redemptions) Add(c schema.Redemption) {
+	if c.Status == "active" && c.ExpiresAt != nil && c.ExpiresAt.Before(r.now) {
+		c.Status = "expired"
+	}
+	switch c.Status {
+	case "used":
+		r.used++
+	case "expired":
+		r.expired++
+	}
+	r.sink.AddRedemption(c)
+}
+
+// Warn reports the number of used and expired codes.
+func (r *Redemptions) Warn() {
+	if r.used > 0 {
+		r.sink.AddWarning(schema.Warning{
+			Code:     schema.WarnRedemptionUsed,
+			Severity: schema.SeverityInfo,
+			Kind:     schema.EntityRedemption,
+			Value:    schema.WarningValue(r.used),
+			Message:  fmt.Sprintf("Exported %d redemption codes that were already used", r.used),
+		})
+	}
+	if r.expired > 0 {
+		r.sink.AddWarning(schema.Warning{
+			Code:     schema.WarnRedemptionExpired,
+			Severity: schema.SeverityInfo,
+			Kind:     schema.EntityRedemption,
+			Value:    schema.WarningValue(r.expired),
+			Message:  fmt.Sprintf("Exported %d redemption codes that have expired", r.expired),
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Package source defines the interface implemented by upstream systems
+// (New API, One API, ...) and a registry keyed by source system name.
+// Implementations register themselves in an init function, so the CLI only
+// needs to import them for their side effects.
+package source
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+	"github.com/EZ-Api/exporter/internal/source/database"
+)
+
+// ExportOptions holds options shared by all source exporters.
+type ExportOptions struct {
+	IncludeTokens         bool // Whether to include tokens in export
+	IncludeAbilities      bool // Whether to include abilities (bindings)
+	IncludeRedemptions    bool // Whether to include redemption codes
+	IncludeAdministrators bool // Whether to include admin and root users
+	BatchSize             int  // Rows read per query (0 = source default)
+	Snapshot              bool // Read all tables in one read-only transaction
+	Verbose               bool // Enable verbose logging
+
+	// Policy for the _original channel backups, one of schema.BackupPolicies
+	// ("" = DefaultBackup)
+	Backup string
+
+	// Quota units per balance unit (USD), used to convert user quotas to
+	// master balances (0 = DefaultQuotaPerUnit)
+	QuotaPerUnit float64
+	// Limits set on every master (0 = leave unset)
+	MaxChildKeys int
+	GlobalQPS    int
+
+	// Incremental export: only rows created or changed at or after Since
+	// (zero = full export)
+	Since time.Time
+	// Entities of the previous export, used to detect hard-deleted rows
+	// (optional, incremental exports only)
+	Baseline *Baseline
+}
+
+// Baseline holds the IDs of the entities in a previous export, keyed by
+// tombstone kind. Rows that were in the baseline but no longer exist in the
+// source are reported as tombstones.
+type Baseline struct {
+	IDs map[string]map[int]string // kind -> original ID -> name ("" for keys)
+}
+
+// NewBaseline collects the entity IDs of a previous export.
+// Providers split from one channel share a single channel ID.
+func NewBaseline(r *schema.ExportResult) *Baseline {
+	b := &Baseline{IDs: map[string]map[int]string{
+		schema.TombstoneProvider: make(map[int]string),
+		schema.TombstoneMaster:   make(map[int]string),
+		schema.TombstoneKey:      make(map[int]string),
+	}}
+
+	for _, p := range r.Data.Providers {
+		name := p.Name
+		if p.OriginalName != "" {
+			name = p.OriginalName
+		}
+		b.IDs[schema.TombstoneProvider][p.OriginalID] = name
+	}
+	for _, m := range r.Data.Masters {
+		b.IDs[schema.TombstoneMaster][m.SourceUserID] = m.Name
+	}
+	for _, k := range r.Data.Keys {
+		b.IDs[schema.TombstoneKey][k.OriginalID] = ""
+	}
+
+	return b
+}
+
+// Tombstones merges the tombstones of soft-deleted rows with those of
+// baseline entities whose ID is not among live (the IDs currently in the
+// source). Soft-deleted entries win, since they carry the deletion time.
+// baseline may be nil. The result is sorted by ID.
+func Tombstones(kind string, softDeleted []schema.Tombstone, baseline *Baseline, live []int) []schema.Tombstone {
+	byID := make(map[int]schema.Tombstone, len(softDeleted))
+	for _, t := range softDeleted {
+		byID[t.OriginalID] = t
+	}
+
+	if baseline != nil {
+		liveIDs := make(map[int]bool, len(live))
+		for _, id := range live {
+			liveIDs[id] = true
+		}
+		for id, name := range baseline.IDs[kind] {
+			if _, ok := byID[id]; ok || liveIDs[id] {
+				continue
+			}
+			byID[id] = schema.Tombstone{Kind: kind, OriginalID: id, Name: name}
+		}
+	}
+
+	tombstones := make([]schema.Tombstone, 0, len(byID))
+	for _, t := range byID {
+		tombstones = append(tombstones, t)
+	}
+	sort.Slice(tombstones, func(i, j int) bool {
+		return tombstones[i].OriginalID < tombstones[j].OriginalID
+	})
+	return tombstones
+}
+
+// DeletedRows finds the deleted rows of a source table.
+type DeletedRows struct {
+	Kind  string // Tombstone kind, one of schema.TombstoneKinds
+	Table string // Row name for errors, e.g. "channel"
+
+	// SoftDeleted returns the tombstones of rows marked as deleted since the
+	// start of the incremental export (nil for tables without soft deletes)
+	SoftDeleted func() ([]schema.Tombstone, error)
+	// LiveIDs returns the IDs currently in the table
+	LiveIDs func() ([]int, error)
+}
+
+// ExportTombstones sends the tombstones of each table's soft-deleted rows
+// and of the baseline entities whose row no longer exists to sink. Live IDs
+// are only read with a baseline to compare them to. baseline may be nil.
+func ExportTombstones(sink schema.Sink, baseline *Baseline, tables []DeletedRows) error {
+	for _, rows := range tables {
+		var softDeleted []schema.Tombstone
+		if rows.SoftDeleted != nil {
+			var err error
+			if softDeleted, err = rows.SoftDeleted(); err != nil {
+				return fmt.Errorf("failed to get deleted %ss: %w", rows.Table, err)
+			}
+		}
+
+		var live []int
+		if baseline != nil {
+			var err error
+			if live, err = rows.LiveIDs(); err != nil {
+				return fmt.Errorf("failed to get %s IDs: %w", rows.Table, err)
+			}
+		}
+
+		for _, t := range Tombstones(rows.Kind, softDeleted, baseline, live) {
+			sink.AddTombstone(t)
+		}
+	}
+	return nil
+}
+
+// SoftDeleteTombstone creates a tombstone for a soft-deleted row.
+func SoftDeleteTombstone(kind string, id int, name string, deletedAt time.Time) schema.Tombstone {
+	deletedAt = deletedAt.UTC()
+	return schema.Tombstone{
+		Kind:       kind,
+		OriginalID: id,
+		Name:       name,
+		DeletedAt:  &deletedAt,
+	}
+}
+
+// Stats holds entity counts of a source database.
+type Stats struct {
+	Channels  int64
+	Tokens    int64
+	Users     int64
+	Abilities int64
+}
+
+// ActiveStats holds counts of active (enabled) entities.
+type ActiveStats struct {
+	Channels int64
+	Tokens   int64
+	Users    int64
+}
+
+// Source is an upstream system that can be exported to the intermediate format.
+type Source interface {
+	// Connect opens the source database.
+	Connect(config database.Config) error
+	// Close closes the source database.
+	Close() error
+	// DetectVersion returns the source system version or version range, or
+	// "unknown".
+	DetectVersion() (string, error)
+	// Stats returns entity counts.
+	Stats() (*Stats, error)
+	// Export sets the source information on sink and sends all entities to it.
+	Export(options ExportOptions, sink schema.Sink) error
+}
+
+// ActiveStatsSource is implemented by sources that can count active entities.
+type ActiveStatsSource interface {
+	ActiveStats() (*ActiveStats, error)
+}
+
+// Factory creates a new, unconnected source.
+type Factory func() Source
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a source available under name.
+// It panics if name is empty or already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("source: Register called with empty name or nil factory")
+	}
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("source: Register called twice for %q", name))
+	}
+	registry[name] = factory
+}
+
+// New creates a new source registered under name.
+func New(name string) (Source, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown source system: %s (available: %v)", name, Names())
+	}
+	return factory(), nil
+}
+
+// Names returns the sorted names of all registered sources.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TypeOverride overrides the compiled-in mapping of one source channel type.
+type TypeOverride struct {
+	// EZ-API provider type ("" = built-in mapping). Required for type IDs the
+	// source does not know.
+	Provider string `yaml:"provider"`
+	// Base URL for channels of this type that have none
+	BaseURL string `yaml:"base_url"`
+}
+
+// TypeMap holds channel type overrides by source system and channel type ID.
+type TypeMap map[string]map[int]TypeOverride
+
+// TypeMapSource is implemented by sources whose channel type mapping can be
+// overridden.
+type TypeMapSource interface {
+	// SetTypeOverrides installs overrides by channel type ID. It fails if an
+	// override cannot be applied, e.g. a new type ID without a provider type.
+	SetTypeOverrides(overrides map[int]TypeOverride) error
+}
+
+var providerTypePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadTypeMap reads a YAML type map file:
+//
+//	newapi:
+//	  57:
+//	    provider: openai
+//	    base_url: https://api.newvendor.example/v1
+//
+// Unknown fields and source systems are rejected.
+func LoadTypeMap(path string) (TypeMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read type map: %w", err)
+	}
+
+	var typeMap TypeMap
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&typeMap); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse type map %s: %w", path, err)
+	}
+
+	if err := typeMap.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid type map %s: %w", path, err)
+	}
+	return typeMap, nil
+}
+
+// Validate checks that every source system is registered and every override
+// is well-formed. Errors are reported for the lowest system and type ID.
+func (m TypeMap) Validate() error {
+	systems := make([]string, 0, len(m))
+	for system := range m {
+		systems = append(systems, system)
+	}
+	sort.Strings(systems)
+
+	for _, system := range systems {
+		if _, err := New(system); err != nil {
+			return err
+		}
+
+		ids := make([]int, 0, len(m[system]))
+		for id := range m[system] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			if id < 0 {
+				return fmt.Errorf("%s type %d: type IDs must not be negative", system, id)
+			}
+			if err := m[system][id].validate(); err != nil {
+				return fmt.Errorf("%s type %d: %w", system, id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (o TypeOverride) validate() error {
+	if o.Provider == "" && o.BaseURL == "" {
+		return errors.New("override sets neither provider nor base_url")
+	}
+	if o.Provider != "" && !providerTypePattern.MatchString(o.Provider) {
+		return fmt.Errorf("provider %q must be lowercase letters, digits, '-' and '_'", o.Provider)
+	}
+	if o.BaseURL != "" {
+		u, err := url.Parse(o.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("base_url %q is not an absolute http(s) URL", o.BaseURL)
+		}
+	}
+	return nil
+}
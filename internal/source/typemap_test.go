@@ -0,0 +1,106 @@
+package source_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/source"
+	_ "github.com/EZ-Api/exporter/internal/source/newapi"
+	_ "github.com/EZ-Api/exporter/internal/source/oneapi"
+)
+
+func TestLoadTypeMap(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want source.TypeMap
+		err  string // substring of the error ("" = no error)
+	}{
+		{
+			name: "valid",
+			yaml: `
+newapi:
+  57:
+    provider: openai
+    base_url: https://api.newvendor.example/v1
+  14:
+    base_url: http://claude-proxy:8080
+oneapi:
+  52:
+    provider: gemini
+`,
+			want: source.TypeMap{
+				"newapi": {
+					57: {Provider: "openai", BaseURL: "https://api.newvendor.example/v1"},
+					14: {BaseURL: "http://claude-proxy:8080"},
+				},
+				"oneapi": {52: {Provider: "gemini"}},
+			},
+		},
+		{name: "empty file", yaml: ``, want: nil},
+		{name: "only comments", yaml: "# no overrides\n", want: nil},
+		{name: "not YAML", yaml: "newapi: [", err: "failed to parse type map"},
+		{name: "unknown field", yaml: "newapi:\n  1:\n    provder: openai\n", err: "field provder not found"},
+		{name: "non-integer type ID", yaml: "newapi:\n  openai:\n    provider: openai\n", err: "failed to parse type map"},
+		{name: "unknown source system", yaml: "foo:\n  1:\n    provider: openai\n", err: "unknown source system: foo"},
+		{name: "negative type ID", yaml: "newapi:\n  -1:\n    provider: openai\n", err: "newapi type -1: type IDs must not be negative"},
+		{name: "empty override", yaml: "newapi:\n  1: {}\n", err: "newapi type 1: override sets neither provider nor base_url"},
+		{name: "display name", yaml: "newapi:\n  1:\n    name: OpenAI\n", err: "field name not found"},
+		{name: "provider with spaces", yaml: "newapi:\n  1:\n    provider: Open AI\n", err: `provider "Open AI" must be lowercase`},
+		{name: "uppercase provider", yaml: "newapi:\n  1:\n    provider: OpenAI\n", err: `provider "OpenAI" must be lowercase`},
+		{name: "relative base URL", yaml: "newapi:\n  1:\n    base_url: api.example.com\n", err: `base_url "api.example.com" is not an absolute http(s) URL`},
+		{name: "non-HTTP base URL", yaml: "newapi:\n  1:\n    base_url: ftp://api.example.com\n", err: "is not an absolute http(s) URL"},
+		{name: "base URL without host", yaml: "newapi:\n  1:\n    base_url: 'https://'\n", err: "is not an absolute http(s) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "types.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := source.LoadTypeMap(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTypeMapMissingFile(t *testing.T) {
+	_, err := source.LoadTypeMap(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "failed to read type map") {
+		t.Fatalf("got %v, want a read error", err)
+	}
+}
+
+func TestTypeMapValidateReportsFirstError(t *testing.T) {
+	m := source.TypeMap{
+		"oneapi": {1: {}},
+		"newapi": {
+			9: {Provider: "Bad"},
+			3: {BaseURL: "nope"},
+			5: {Provider: "openai"},
+		},
+	}
+	err := m.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "newapi type 3:") {
+		t.Fatalf("got %v, want the error of newapi type 3", err)
+	}
+
+	if err := (source.TypeMap{}).Validate(); err != nil {
+		t.Errorf("empty type map: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package source
+
+import (
+	"fmt"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// Masters sends the masters converted from users to a sink and matches the
+// tokens read afterwards to their master. Tokens without a master and
+// tokens of admin users are reported by Warn once all tokens are read.
+type Masters struct {
+	sink        schema.Sink
+	byUser      map[int]schema.Master // Source user ID -> master
+	admins      []schema.Master       // Masters of admin users, in user order
+	adminTokens map[int]int           // Admin source user ID -> exported tokens
+	orphaned    int
+}
+
+// NewMasters creates a master index sending masters to sink.
+func NewMasters(sink schema.Sink) *Masters {
+	return &Masters{
+		sink:        sink,
+		byUser:      make(map[int]schema.Master),
+		adminTokens: make(map[int]int),
+	}
+}
+
+// Add sends a master to the sink and indexes it by source user ID.
+func (m *Masters) Add(master schema.Master) {
+	m.sink.AddMaster(master)
+	m.byUser[master.SourceUserID] = master
+	if master.Role == schema.RoleAdmin || master.Role == schema.RoleRoot {
+		m.admins = append(m.admins, master)
+		m.adminTokens[master.SourceUserID] = 0
+	}
+}
+
+// TokenMaster returns the master of the user owning a token and counts the
+// token. Returns false for tokens whose user was not exported; they are
+// skipped.
+func (m *Masters) TokenMaster(userID int) (schema.Master, bool) {
+	master, ok := m.byUser[userID]
+	if !ok {
+		m.orphaned++
+		return schema.Master{}, false
+	}
+	if n, ok := m.adminTokens[userID]; ok {
+		m.adminTokens[userID] = n + 1
+	}
+	return master, true
+}
+
+// Warn reports the skipped tokens and, for each admin user with tokens,
+// that the tokens do not carry admin rights.
+func (m *Masters) Warn() {
+	if m.orphaned > 0 {
+		m.sink.AddWarning(schema.Warning{
+			Code:     schema.WarnOrphanedTokens,
+			Severity: schema.SeverityWarning,
+			Kind:     schema.EntityKey,
+			Value:    schema.WarningValue(m.orphaned),
+			Message:  fmt.Sprintf("Skipped %d tokens whose user does not exist or is deleted", m.orphaned),
+		})
+	}
+
+	for _, master := range m.admins {
+		if n := m.adminTokens[master.SourceUserID]; n > 0 {
+			m.sink.AddWarning(schema.Warning{
+				Code:       schema.WarnAdminTokenScopes,
+				Severity:   schema.SeverityWarning,
+				Kind:       schema.EntityMaster,
+				OriginalID: master.SourceUserID,
+				Name:       master.Name,
+				Value:      schema.WarningValue(n),
+				Message:    fmt.Sprintf("User '%s' (ID=%d) is %s and has %d tokens; they are exported with normal key scopes and do not carry admin rights", master.Name, master.SourceUserID, master.Role, n),
+			})
+		}
+	}
+}
+
+// Identities returns the linked accounts that have a subject.
+func Identities(accounts ...schema.Identity) []schema.Identity {
+	var identities []schema.Identity
+	for _, a := range accounts {
+		if a.Subject != "" {
+			identities = append(identities, a)
+		}
+	}
+	return identities
+}
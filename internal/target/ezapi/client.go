@@ -0,0 +1,161 @@
+// Package ezapi provides a client for importing exported data into a
+// running EZ-API instance through its admin HTTP API.
+//
+// Every entity is upserted by its natural key: the collection is queried
+// with a lookup filter, and the entity is created with POST when no match
+// exists or replaced with PUT /{collection}/{id} otherwise. Re-running a
+// push therefore converges on the same state instead of duplicating rows.
+package ezapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClientConfig holds admin API connection configuration.
+type ClientConfig struct {
+	BaseURL    string        // EZ-API base URL, e.g. "http://localhost:8080"
+	AdminToken string        // Admin API token (sent as Bearer token)
+	Timeout    time.Duration // Per-request timeout
+}
+
+// Client is a minimal EZ-API admin API client.
+type Client struct {
+	baseURL    string
+	adminToken string
+	http       *http.Client
+}
+
+// NewClient creates a new admin API client.
+func NewClient(config ClientConfig) (*Client, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+	if _, err := url.Parse(config.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(config.BaseURL, "/"),
+		adminToken: config.AdminToken,
+		http:       &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// APIError is returned when the admin API responds with a non-2xx status.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// entity is the minimal shape of an admin API entity response.
+type entity struct {
+	ID int64 `json:"id"`
+}
+
+// UpsertResult describes the outcome of a single upsert.
+type UpsertResult struct {
+	ID      int64 // Entity ID assigned by EZ-API
+	Created bool  // True if the entity did not exist before
+}
+
+// Upsert creates or replaces the entity identified by lookup in collection.
+func (c *Client) Upsert(ctx context.Context, collection string, lookup url.Values, body interface{}) (UpsertResult, error) {
+	var existing []entity
+	if err := c.do(ctx, http.MethodGet, collection+"?"+lookup.Encode(), nil, &existing); err != nil {
+		return UpsertResult{}, fmt.Errorf("lookup failed: %w", err)
+	}
+
+	if len(existing) > 1 {
+		return UpsertResult{}, fmt.Errorf("lookup %s matched %d entities", lookup.Encode(), len(existing))
+	}
+
+	if len(existing) == 1 {
+		var updated entity
+		path := fmt.Sprintf("%s/%d", collection, existing[0].ID)
+		if err := c.do(ctx, http.MethodPut, path, body, &updated); err != nil {
+			return UpsertResult{}, fmt.Errorf("update failed: %w", err)
+		}
+		if updated.ID == 0 {
+			updated.ID = existing[0].ID
+		}
+		return UpsertResult{ID: updated.ID}, nil
+	}
+
+	var created entity
+	if err := c.do(ctx, http.MethodPost, collection, body, &created); err != nil {
+		return UpsertResult{}, fmt.Errorf("create failed: %w", err)
+	}
+	return UpsertResult{ID: created.ID, Created: true}, nil
+}
+
+// do performs a JSON request and decodes the response into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{
+			Method:     method,
+			Path:       strings.SplitN(path, "?", 2)[0],
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(data)),
+		}
+	}
+
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
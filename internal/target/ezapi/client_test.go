@@ -0,0 +1,307 @@
+package ezapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "admin-secret"
+
+// fakeAPI is an in-memory EZ-API admin API. Collections are keyed by path
+// and hold the decoded request bodies with an assigned "id".
+type fakeAPI struct {
+	t *testing.T
+
+	mu          sync.Mutex
+	nextID      int64
+	collections map[string][]map[string]interface{}
+	requests    []string // "METHOD path", without the query
+
+	// fail, when set, returns a status to respond with instead of handling
+	// the request (0 = handle normally).
+	fail func(method, path string) int
+}
+
+// newFakeAPI starts a fake admin API and returns it with a client for it.
+func newFakeAPI(t *testing.T) (*fakeAPI, *Client) {
+	t.Helper()
+
+	api := &fakeAPI{t: t, collections: map[string][]map[string]interface{}{}}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(ClientConfig{BaseURL: server.URL + "/", AdminToken: testToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api, client
+}
+
+// seed adds an entity to a collection and returns its ID.
+func (a *fakeAPI) seed(collection string, fields map[string]interface{}) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nextID++
+	fields["id"] = float64(a.nextID)
+	a.collections[collection] = append(a.collections[collection], fields)
+	return a.nextID
+}
+
+// failOn returns a fail function responding with status to every request
+// with method.
+func failOn(method string, status int) func(string, string) int {
+	return func(m, _ string) int {
+		if m == method {
+			return status
+		}
+		return 0
+	}
+}
+
+// Requests returns the requests received so far.
+func (a *fakeAPI) Requests() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.requests...)
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests = append(a.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if a.fail != nil {
+		if status := a.fail(r.Method, r.URL.Path); status != 0 {
+			http.Error(w, `{"error":"injected failure"}`, status)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var matches []map[string]interface{}
+		for _, e := range a.collections[r.URL.Path] {
+			if matchesLookup(e, r.URL.Query()) {
+				matches = append(matches, e)
+			}
+		}
+		a.reply(w, http.StatusOK, matches)
+
+	case http.MethodPost:
+		body := a.decode(r)
+		a.nextID++
+		body["id"] = float64(a.nextID)
+		a.collections[r.URL.Path] = append(a.collections[r.URL.Path], body)
+		a.reply(w, http.StatusCreated, body)
+
+	case http.MethodPut:
+		collection, idText := path.Split(r.URL.Path)
+		collection = strings.TrimSuffix(collection, "/")
+		id, err := strconv.ParseFloat(idText, 64)
+		if err != nil {
+			http.Error(w, "bad id", http.StatusBadRequest)
+			return
+		}
+		for i, e := range a.collections[collection] {
+			if e["id"] == id {
+				body := a.decode(r)
+				body["id"] = id
+				a.collections[collection][i] = body
+				a.reply(w, http.StatusOK, body)
+				return
+			}
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// matchesLookup reports whether every lookup filter matches a field of e.
+// Exported fields prefixed with "_" (such as _original_id) match without it.
+func matchesLookup(e map[string]interface{}, lookup url.Values) bool {
+	for name := range lookup {
+		value, ok := e[name]
+		if !ok {
+			value = e["_"+name]
+		}
+		if fmt.Sprint(value) != lookup.Get(name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *fakeAPI) decode(r *http.Request) map[string]interface{} {
+	if r.Header.Get("Content-Type") != "application/json" {
+		a.t.Errorf("%s %s: Content-Type %q", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		a.t.Errorf("%s %s: invalid body: %v", r.Method, r.URL.Path, err)
+	}
+	return body
+}
+
+func (a *fakeAPI) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestUpsertCreatesMissing(t *testing.T) {
+	api, client := newFakeAPI(t)
+
+	res, err := client.Upsert(context.Background(), "/admin/providers", url.Values{"name": {"openai"}},
+		map[string]string{"name": "openai", "type": "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Created || res.ID == 0 {
+		t.Errorf("got %+v, want a created entity", res)
+	}
+
+	want := []string{"GET /admin/providers", "POST /admin/providers"}
+	if got := api.Requests(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("requests %v, want %v", got, want)
+	}
+}
+
+func TestUpsertUpdatesExisting(t *testing.T) {
+	api, client := newFakeAPI(t)
+	api.seed("/admin/providers", map[string]interface{}{"name": "other"})
+	id := api.seed("/admin/providers", map[string]interface{}{"name": "openai", "type": "azure"})
+
+	res, err := client.Upsert(context.Background(), "/admin/providers", url.Values{"name": {"openai"}},
+		map[string]string{"name": "openai", "type": "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created || res.ID != id {
+		t.Errorf("got %+v, want update of ID %d", res, id)
+	}
+
+	want := []string{"GET /admin/providers", fmt.Sprintf("PUT /admin/providers/%d", id)}
+	if got := api.Requests(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("requests %v, want %v", got, want)
+	}
+	if got := api.collections["/admin/providers"][1]["type"]; got != "openai" {
+		t.Errorf("type after update = %v, want openai", got)
+	}
+}
+
+func TestUpsertAmbiguousLookup(t *testing.T) {
+	api, client := newFakeAPI(t)
+	api.seed("/admin/providers", map[string]interface{}{"name": "openai"})
+	api.seed("/admin/providers", map[string]interface{}{"name": "openai"})
+
+	_, err := client.Upsert(context.Background(), "/admin/providers", url.Values{"name": {"openai"}}, map[string]string{})
+	if err == nil || !strings.Contains(err.Error(), "matched 2 entities") {
+		t.Fatalf("got %v, want an ambiguous lookup error", err)
+	}
+	if got := api.Requests(); len(got) != 1 {
+		t.Errorf("requests %v, want only the lookup", got)
+	}
+}
+
+func TestUpsertErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		fail   func(method, path string) int
+		status int
+		path   string
+		prefix string
+	}{
+		{
+			name:   "wrong token",
+			token:  "wrong",
+			status: http.StatusUnauthorized,
+			path:   "/admin/providers",
+			prefix: "lookup failed",
+		},
+		{
+			name:   "no token",
+			status: http.StatusUnauthorized,
+			path:   "/admin/providers",
+			prefix: "lookup failed",
+		},
+		{
+			name:   "lookup error",
+			token:  testToken,
+			fail:   failOn(http.MethodGet, http.StatusInternalServerError),
+			status: http.StatusInternalServerError,
+			path:   "/admin/providers",
+			prefix: "lookup failed",
+		},
+		{
+			name:   "create rejected",
+			token:  testToken,
+			fail:   failOn(http.MethodPost, http.StatusUnprocessableEntity),
+			status: http.StatusUnprocessableEntity,
+			path:   "/admin/providers",
+			prefix: "create failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, client := newFakeAPI(t)
+			api.fail = tt.fail
+			client.adminToken = tt.token
+
+			_, err := client.Upsert(context.Background(), "/admin/providers", url.Values{"name": {"x"}}, map[string]string{"name": "x"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want an APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Path != tt.path {
+				t.Errorf("got HTTP %d on %s, want HTTP %d on %s", apiErr.StatusCode, apiErr.Path, tt.status, tt.path)
+			}
+			if apiErr.Body == "" {
+				t.Error("error body not kept")
+			}
+			if !strings.HasPrefix(err.Error(), tt.prefix) {
+				t.Errorf("error %q does not start with %q", err, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestUpsertUpdateRejected(t *testing.T) {
+	api, client := newFakeAPI(t)
+	id := api.seed("/admin/masters", map[string]interface{}{"name": "alice"})
+	api.fail = failOn(http.MethodPut, http.StatusConflict)
+
+	_, err := client.Upsert(context.Background(), "/admin/masters", url.Values{"name": {"alice"}}, map[string]string{"name": "alice"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("got %v, want HTTP 409", err)
+	}
+	if want := fmt.Sprintf("/admin/masters/%d", id); apiErr.Path != want {
+		t.Errorf("path %s, want %s", apiErr.Path, want)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(ClientConfig{}); err == nil {
+		t.Error("empty base URL accepted")
+	}
+	if _, err := NewClient(ClientConfig{BaseURL: "http://[::1"}); err == nil {
+		t.Error("invalid base URL accepted")
+	}
+}
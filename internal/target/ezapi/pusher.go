@@ -0,0 +1,230 @@
+// Push logic for importing an export into EZ-API.
+
+package ezapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// Entity kinds, in the order they are pushed.
+const (
+	KindProvider = "provider"
+	KindMaster   = "master"
+	KindKey      = "key"
+	KindBinding  = "binding"
+)
+
+// PusherConfig holds configuration for the pusher.
+type PusherConfig struct {
+	ProgressLog string // Path of the resumable progress log (empty = disabled)
+}
+
+// Failure records an entity that could not be pushed.
+type Failure struct {
+	Kind string // Entity kind
+	Ref  string // Natural key of the entity
+	Err  error  // Underlying error
+}
+
+// Unpushed counts the entities of an export section that the pusher does
+// not import, since EZ-API has no admin API for them.
+type Unpushed struct {
+	Section string // Data section, e.g. "redemptions"
+	Count   int    // Entities in the section
+}
+
+// Report summarizes a push run.
+type Report struct {
+	Created   int        // Entities created
+	Updated   int        // Existing entities replaced
+	Skipped   int        // Entities already recorded in the progress log
+	Failures  []Failure  // Entities that failed
+	NotPushed []Unpushed // Non-empty sections that were not pushed
+}
+
+// Pusher imports an export result into EZ-API in dependency order:
+// providers, masters, keys (which need their master's ID), then bindings.
+type Pusher struct {
+	client   *Client
+	config   PusherConfig
+	done     map[string]int64 // "kind/ref" -> EZ-API ID, from progress log
+	progress *os.File
+	report   *Report
+}
+
+// NewPusher creates a new pusher instance.
+func NewPusher(client *Client, config PusherConfig) *Pusher {
+	return &Pusher{
+		client: client,
+		config: config,
+		done:   make(map[string]int64),
+	}
+}
+
+// progressEntry is a single line of the progress log.
+type progressEntry struct {
+	Kind string `json:"kind"`
+	Ref  string `json:"ref"`
+	ID   int64  `json:"id"`
+}
+
+// Push imports all entities of result. Failures of individual entities are
+// collected in the report and do not abort the run; an error is returned only
+// if the push cannot proceed at all (e.g. the export is redacted or the
+// progress log is unusable).
+func (p *Pusher) Push(ctx context.Context, result *schema.ExportResult) (*Report, error) {
+	if result.Source.Redaction != "" {
+		return nil, fmt.Errorf("export is redacted (%s): API keys and tokens cannot be imported", result.Source.Redaction)
+	}
+
+	p.report = &Report{}
+
+	if p.config.ProgressLog != "" {
+		if err := p.loadProgress(); err != nil {
+			return nil, fmt.Errorf("failed to load progress log: %w", err)
+		}
+		f, err := os.OpenFile(p.config.ProgressLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open progress log: %w", err)
+		}
+		p.progress = f
+		defer func() {
+			p.progress.Close()
+			p.progress = nil
+		}()
+	}
+
+	for _, provider := range result.Data.Providers {
+		p.upsert(ctx, KindProvider, provider.Name, "/admin/providers",
+			url.Values{"name": {provider.Name}}, provider)
+	}
+
+	for _, master := range result.Data.Masters {
+		p.upsert(ctx, KindMaster, master.Name, "/admin/masters",
+			url.Values{"name": {master.Name}}, master)
+	}
+
+	for _, key := range result.Data.Keys {
+		ref := strconv.Itoa(key.OriginalID)
+		masterID, ok := p.done[progressKey(KindMaster, key.MasterRef)]
+		if !ok {
+			p.fail(KindKey, ref, fmt.Errorf("master '%s' was not pushed", key.MasterRef))
+			continue
+		}
+		p.upsert(ctx, KindKey, ref, fmt.Sprintf("/admin/masters/%d/keys", masterID),
+			url.Values{"original_id": {ref}}, key)
+	}
+
+	for _, binding := range result.Data.Bindings {
+		ref := fmt.Sprintf("%s/%s/%s", binding.Namespace, binding.RouteGroup, binding.Model)
+		p.upsert(ctx, KindBinding, ref, "/admin/bindings", url.Values{
+			"namespace":   {binding.Namespace},
+			"route_group": {binding.RouteGroup},
+			"model":       {binding.Model},
+		}, binding)
+	}
+
+	p.report.NotPushed = unpushedSections(result)
+
+	return p.report, nil
+}
+
+// unpushedSections returns the non-empty sections of result that are not
+// pushed. Tombstones are not included; deletions are left to the operator.
+func unpushedSections(result *schema.ExportResult) []Unpushed {
+	var unpushed []Unpushed
+	for _, u := range []Unpushed{
+		{Section: "failover_groups", Count: len(result.Data.FailoverGroups)},
+		{Section: "redemptions", Count: len(result.Data.Redemptions)},
+		{Section: "administrators", Count: len(result.Data.Administrators)},
+	} {
+		if u.Count > 0 {
+			unpushed = append(unpushed, u)
+		}
+	}
+	return unpushed
+}
+
+// upsert pushes a single entity unless the progress log shows it as done.
+func (p *Pusher) upsert(ctx context.Context, kind, ref, collection string, lookup url.Values, body interface{}) {
+	if _, ok := p.done[progressKey(kind, ref)]; ok {
+		p.report.Skipped++
+		return
+	}
+
+	res, err := p.client.Upsert(ctx, collection, lookup, body)
+	if err != nil {
+		p.fail(kind, ref, err)
+		return
+	}
+
+	if res.Created {
+		p.report.Created++
+	} else {
+		p.report.Updated++
+	}
+
+	p.done[progressKey(kind, ref)] = res.ID
+	if err := p.recordProgress(progressEntry{Kind: kind, Ref: ref, ID: res.ID}); err != nil {
+		p.fail(kind, ref, fmt.Errorf("pushed but failed to record progress: %w", err))
+	}
+}
+
+// fail records a failed entity.
+func (p *Pusher) fail(kind, ref string, err error) {
+	p.report.Failures = append(p.report.Failures, Failure{Kind: kind, Ref: ref, Err: err})
+}
+
+// loadProgress reads previously completed entities from the progress log.
+func (p *Pusher) loadProgress() error {
+	f, err := os.Open(p.config.ProgressLog)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry progressEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		p.done[progressKey(entry.Kind, entry.Ref)] = entry.ID
+	}
+	return scanner.Err()
+}
+
+// recordProgress appends a completed entity to the progress log.
+func (p *Pusher) recordProgress(entry progressEntry) error {
+	if p.progress == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = p.progress.Write(append(data, '\n'))
+	return err
+}
+
+// progressKey builds the lookup key for completed entities.
+func progressKey(kind, ref string) string {
+	return kind + "/" + ref
+}
@@ -0,0 +1,221 @@
+package ezapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EZ-Api/exporter/internal/schema"
+)
+
+// testExport returns an export with two providers, two masters with one key
+// each, a binding and sections the pusher does not import.
+func testExport() *schema.ExportResult {
+	result := schema.NewExportResult()
+	result.Data.Providers = []schema.Provider{
+		{OriginalID: 1, Name: "openai", Type: "openai", APIKey: "sk-1", Status: "active"},
+		{OriginalID: 2, Name: "claude", Type: "anthropic", APIKey: "sk-2", Status: "active"},
+	}
+	result.Data.Masters = []schema.Master{
+		{Name: "alice", Group: "default", Status: "active", SourceUserID: 1},
+		{Name: "bob", Group: "default", Status: "active", SourceUserID: 2},
+	}
+	result.Data.Keys = []schema.Key{
+		{MasterRef: "alice", OriginalToken: "tok-a", Status: "active", OriginalID: 10},
+		{MasterRef: "bob", OriginalToken: "tok-b", Status: "active", OriginalID: 11},
+	}
+	result.Data.Bindings = []schema.Binding{
+		{Namespace: "default", RouteGroup: "default", Model: "gpt-4o", Status: "active"},
+	}
+	result.Data.FailoverGroups = []schema.FailoverGroup{{Group: "default", Model: "gpt-4o"}}
+	result.Data.Redemptions = []schema.Redemption{{Name: "a"}, {Name: "b"}}
+	return result
+}
+
+func push(t *testing.T, client *Client, config PusherConfig, result *schema.ExportResult) *Report {
+	t.Helper()
+	report, err := NewPusher(client, config).Push(context.Background(), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestPushCreatesThenUpdates(t *testing.T) {
+	api, client := newFakeAPI(t)
+	result := testExport()
+
+	report := push(t, client, PusherConfig{}, result)
+	if report.Created != 7 || report.Updated != 0 || len(report.Failures) != 0 {
+		t.Fatalf("first push: %+v, want 7 created", report)
+	}
+
+	// Keys are created under the EZ-API ID of their master
+	for _, m := range api.collections["/admin/masters"] {
+		collection := fmt.Sprintf("/admin/masters/%v/keys", m["id"])
+		keys := api.collections[collection]
+		if len(keys) != 1 || keys[0]["master_ref"] != m["name"] {
+			t.Errorf("%s holds %v, want the key of %v", collection, keys, m["name"])
+		}
+	}
+
+	report = push(t, client, PusherConfig{}, result)
+	if report.Created != 0 || report.Updated != 7 || len(report.Failures) != 0 {
+		t.Fatalf("second push: %+v, want 7 updated", report)
+	}
+	if n := len(api.collections["/admin/providers"]); n != 2 {
+		t.Errorf("%d providers after re-push, want 2", n)
+	}
+}
+
+func TestPushReportsUnpushedSections(t *testing.T) {
+	_, client := newFakeAPI(t)
+	result := testExport()
+	result.Data.Administrators = []schema.Administrator{{Name: "root"}}
+
+	report := push(t, client, PusherConfig{}, result)
+	want := []Unpushed{
+		{Section: "failover_groups", Count: 1},
+		{Section: "redemptions", Count: 2},
+		{Section: "administrators", Count: 1},
+	}
+	if fmt.Sprint(report.NotPushed) != fmt.Sprint(want) {
+		t.Errorf("not pushed %v, want %v", report.NotPushed, want)
+	}
+
+	result.Data.FailoverGroups, result.Data.Redemptions, result.Data.Administrators = nil, nil, nil
+	if report := push(t, client, PusherConfig{}, result); report.NotPushed != nil {
+		t.Errorf("not pushed %v, want none", report.NotPushed)
+	}
+}
+
+func TestPushRefusesRedactedExport(t *testing.T) {
+	api, client := newFakeAPI(t)
+	result := testExport()
+	result.Source.Redaction = schema.RedactMask
+
+	_, err := NewPusher(client, PusherConfig{}).Push(context.Background(), result)
+	if err == nil || !strings.Contains(err.Error(), "redacted") {
+		t.Fatalf("got %v, want a redacted export error", err)
+	}
+	if got := api.Requests(); len(got) != 0 {
+		t.Errorf("requests %v, want none", got)
+	}
+}
+
+func TestPushFailures(t *testing.T) {
+	api, client := newFakeAPI(t)
+	// Reject bob, so his key cannot be pushed either
+	api.fail = func(method, path string) int {
+		if method == http.MethodPost && path == "/admin/masters" && len(api.collections[path]) == 1 {
+			return http.StatusBadRequest
+		}
+		return 0
+	}
+
+	report := push(t, client, PusherConfig{}, testExport())
+	if report.Created != 5 {
+		t.Errorf("created %d, want 5", report.Created)
+	}
+	want := []string{"master 'bob'", "key '11'"}
+	if len(report.Failures) != len(want) {
+		t.Fatalf("failures %v, want %v", report.Failures, want)
+	}
+	for i, f := range report.Failures {
+		if got := fmt.Sprintf("%s '%s'", f.Kind, f.Ref); got != want[i] {
+			t.Errorf("failure %d: %s: %v, want %s", i, got, f.Err, want[i])
+		}
+	}
+	if !strings.Contains(report.Failures[1].Err.Error(), "master 'bob' was not pushed") {
+		t.Errorf("key failure: %v", report.Failures[1].Err)
+	}
+}
+
+func TestPushResumesFromProgressLog(t *testing.T) {
+	api, client := newFakeAPI(t)
+	progress := filepath.Join(t.TempDir(), "push.progress")
+	config := PusherConfig{ProgressLog: progress}
+	result := testExport()
+
+	// The first run fails on keys, e.g. because the instance went away
+	api.fail = func(method, path string) int {
+		if strings.HasSuffix(path, "/keys") {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	report := push(t, client, config, result)
+	if report.Created != 5 || len(report.Failures) != 2 {
+		t.Fatalf("first run: %+v, want 5 created and 2 failures", report)
+	}
+
+	entries := readProgress(t, progress)
+	if len(entries) != 5 {
+		t.Fatalf("progress log has %d entries, want 5: %v", len(entries), entries)
+	}
+	if info, err := os.Stat(progress); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("progress log mode %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+
+	// The second run only pushes the keys, using the master IDs of the log
+	api.fail = nil
+	before := len(api.Requests())
+	report = push(t, client, config, result)
+	if report.Skipped != 5 || report.Created != 2 || len(report.Failures) != 0 {
+		t.Fatalf("second run: %+v, want 5 skipped and 2 created", report)
+	}
+	for _, req := range api.Requests()[before:] {
+		if !strings.HasSuffix(req, "/keys") {
+			t.Errorf("resumed run sent %s", req)
+		}
+	}
+	if entries := readProgress(t, progress); len(entries) != 7 {
+		t.Errorf("progress log has %d entries, want 7", len(entries))
+	}
+
+	// A third run has nothing left to do
+	before = len(api.Requests())
+	report = push(t, client, config, result)
+	if report.Skipped != 7 || len(api.Requests()) != before {
+		t.Errorf("third run: %+v with %d requests, want everything skipped", report, len(api.Requests())-before)
+	}
+}
+
+func TestPushInvalidProgressLog(t *testing.T) {
+	_, client := newFakeAPI(t)
+	progress := filepath.Join(t.TempDir(), "push.progress")
+	if err := os.WriteFile(progress, []byte("{\"kind\":\"provider\",\"ref\":\"openai\",\"id\":1}\nnot json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewPusher(client, PusherConfig{ProgressLog: progress}).Push(context.Background(), testExport())
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("got %v, want an error on line 2", err)
+	}
+}
+
+func readProgress(t *testing.T, path string) []progressEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []progressEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry progressEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}